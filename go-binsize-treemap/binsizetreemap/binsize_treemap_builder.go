@@ -0,0 +1,135 @@
+package binsizetreemap
+
+import (
+	"context"
+	"errors"
+
+	"github.com/nikolaydubina/go-instrument-example/treemap"
+)
+
+// BinarySizeTreemapBuilder creates single treemap tree where each leaf is symbol.
+// Symbols are grouped by module from build info, then by package in module.
+// Size is bytes.
+// If baseline binary is given, then heat is growth of size, mapped to 0~1 where 0.5 is no change.
+// Size of parents is not set, so that it can be imputed from children.
+type BinarySizeTreemapBuilder struct{}
+
+// NewBinarySizeTreemapBuilder is constructor.
+func NewBinarySizeTreemapBuilder() BinarySizeTreemapBuilder {
+	return BinarySizeTreemapBuilder{}
+}
+
+// BinarySizeTreemapFromBinary from single binary, it has no heat.
+func (s BinarySizeTreemapBuilder) BinarySizeTreemapFromBinary(ctx context.Context, binary *Binary) (*treemap.Tree, error) {
+	return s.BinarySizeDiffTreemapFromBinaries(ctx, nil, binary)
+}
+
+// BinarySizeDiffTreemapFromBinaries from current binary compared to baseline.
+// Heat of packages is computed from change of their total size, not from heat of children.
+// Symbols that were removed are not in tree, but they are accounted in heat of their parents.
+func (s BinarySizeTreemapBuilder) BinarySizeDiffTreemapFromBinaries(ctx context.Context, base *Binary, curr *Binary) (*treemap.Tree, error) {
+	if curr == nil || len(curr.Symbols) == 0 {
+		return nil, errors.New("no symbols")
+	}
+
+	sizes, symbols := symbolSizes(curr)
+
+	tree := treemap.Tree{
+		Nodes: map[string]treemap.Node{},
+		To:    map[string][]string{},
+	}
+
+	// for finding roots
+	hasParent := map[string]bool{}
+
+	for _, sym := range symbols {
+		path := sym.path()
+		tree.Nodes[path] = treemap.Node{
+			Path: path,
+			Name: sym.name,
+			Size: float64(sizes[path]),
+		}
+		addSymbolParents(&tree, hasParent, sym)
+	}
+
+	if base != nil {
+		baseTotals := totalSizes(symbolSizes(base))
+		currTotals := totalSizes(sizes, symbols)
+
+		for path, node := range tree.Nodes {
+			node.Heat = growthHeat(baseTotals[path], currTotals[path])
+			node.HasHeat = true
+			tree.Nodes[path] = node
+		}
+	}
+
+	if err := treemap.SetRoot(&tree, hasParent); err != nil {
+		return nil, err
+	}
+
+	return &tree, nil
+}
+
+// symbolSizes sums sizes of symbols with same path.
+// Symbols are in order of first appearance.
+func symbolSizes(binary *Binary) (map[string]uint64, []symbolPath) {
+	sizes := map[string]uint64{}
+	var symbols []symbolPath
+	for _, sym := range binary.Symbols {
+		p := newSymbolPath(sym.Name, binary.Modules)
+		if _, ok := sizes[p.path()]; !ok {
+			symbols = append(symbols, p)
+		}
+		sizes[p.path()] += sym.Size
+	}
+	return sizes, symbols
+}
+
+// addSymbolParents adds module and package nodes of symbol.
+// Names are set, since module and package paths have "/".
+func addSymbolParents(tree *treemap.Tree, hasParent map[string]bool, sym symbolPath) {
+	tree.Nodes[sym.module] = treemap.Node{
+		Path: sym.module,
+		Name: sym.module,
+	}
+	if sym.pkg != "" {
+		tree.Nodes[sym.packagePath()] = treemap.Node{
+			Path: sym.packagePath(),
+			Name: sym.pkg,
+		}
+		tree.To[sym.module] = append(tree.To[sym.module], sym.packagePath())
+		hasParent[sym.packagePath()] = true
+	}
+	tree.To[sym.packagePath()] = append(tree.To[sym.packagePath()], sym.path())
+
+	if _, ok := hasParent[sym.module]; !ok {
+		hasParent[sym.module] = false
+	}
+	hasParent[sym.path()] = true
+}
+
+// totalSizes is size of each node including all its children.
+func totalSizes(sizes map[string]uint64, symbols []symbolPath) map[string]uint64 {
+	totals := map[string]uint64{}
+	for _, sym := range symbols {
+		v := sizes[sym.path()]
+		totals[sym.path()] += v
+		if sym.pkg != "" {
+			totals[sym.packagePath()] += v
+		}
+		totals[sym.module] += v
+	}
+	return totals
+}
+
+// growthHeat maps relative change of size in range -1~1 to 0~1.
+func growthHeat(base, curr uint64) float64 {
+	if base == curr {
+		return 0.5
+	}
+	m := base
+	if curr > m {
+		m = curr
+	}
+	return 0.5 + (float64(curr)-float64(base))/float64(m)/2
+}
@@ -0,0 +1,194 @@
+package binsizetreemap
+
+import (
+	"debug/buildinfo"
+	"debug/elf"
+	"debug/gosym"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Symbol is sized symbol of binary.
+// Name is full Go symbol name, like `github.com/a/b.(*T).Method`.
+type Symbol struct {
+	Name string
+	Size uint64
+}
+
+// Binary is symbols and modules of Go binary.
+// Main module is first of modules.
+type Binary struct {
+	Symbols []Symbol
+	Modules []string
+}
+
+// ReadELFBinary reads functions from Go line table and data objects from ELF symbol table.
+// Objects in zero initialized sections like .bss are skipped.
+// Stripped binaries do not have ELF symbol table, so only functions are read for them.
+// Modules are from build info, if it is present.
+func ReadELFBinary(r io.ReaderAt) (*Binary, error) {
+	f, err := elf.NewFile(r)
+	if err != nil {
+		return nil, fmt.Errorf("can not open ELF: %w", err)
+	}
+	defer f.Close()
+
+	text := f.Section(".text")
+	pclntab := f.Section(".gopclntab")
+	if text == nil || pclntab == nil {
+		return nil, errors.New("not a Go binary, no .text or .gopclntab section")
+	}
+	pclndata, err := pclntab.Data()
+	if err != nil {
+		return nil, fmt.Errorf("can not read .gopclntab: %w", err)
+	}
+
+	var symtab []byte
+	if s := f.Section(".gosymtab"); s != nil {
+		if symtab, err = s.Data(); err != nil {
+			return nil, fmt.Errorf("can not read .gosymtab: %w", err)
+		}
+	}
+
+	table, err := gosym.NewTable(symtab, gosym.NewLineTable(pclndata, text.Addr))
+	if err != nil {
+		return nil, fmt.Errorf("can not read Go symbol table: %w", err)
+	}
+
+	var symbols []Symbol
+	for _, fn := range table.Funcs {
+		if fn.End <= fn.Entry {
+			continue
+		}
+		symbols = append(symbols, Symbol{Name: fn.Name, Size: fn.End - fn.Entry})
+	}
+
+	elfSymbols, err := f.Symbols()
+	if err != nil && !errors.Is(err, elf.ErrNoSymbols) {
+		return nil, fmt.Errorf("can not read ELF symbols: %w", err)
+	}
+	for _, s := range elfSymbols {
+		if elf.ST_TYPE(s.Info) != elf.STT_OBJECT || s.Size == 0 {
+			continue
+		}
+		// zero initialized data does not take space in file
+		if i := int(s.Section); i < len(f.Sections) && f.Sections[i].Type == elf.SHT_NOBITS {
+			continue
+		}
+		symbols = append(symbols, Symbol{Name: s.Name, Size: s.Size})
+	}
+
+	var modules []string
+	if info, err := buildinfo.Read(r); err == nil {
+		modules = append(modules, info.Main.Path)
+		for _, dep := range info.Deps {
+			modules = append(modules, dep.Path)
+		}
+	}
+
+	return &Binary{Symbols: symbols, Modules: modules}, nil
+}
+
+// symbolPath is module, package and rest of symbol name.
+// Package is directory relative to module, "." for root package, rest of name has "/" replaced with "_".
+// Standard library is module `std`, symbols without package are in module `other` without package.
+// Main package is in main module, packages that are not in modules are module by themselves.
+// Type arguments are collapsed, so that instantiations of same function are summed.
+type symbolPath struct {
+	module string
+	pkg    string
+	name   string
+}
+
+func newSymbolPath(name string, modules []string) symbolPath {
+	name = collapseTypeArgs(name)
+
+	pkg := packageName(name)
+	if pkg == "" {
+		return symbolPath{module: "other", name: strings.ReplaceAll(name, "/", "_")}
+	}
+	rest := strings.ReplaceAll(name[len(pkg)+1:], "/", "_")
+
+	var module string
+	switch {
+	case pkg == "main" && len(modules) > 0 && modules[0] != "":
+		module = modules[0]
+	case isStd(pkg, modules):
+		return symbolPath{module: "std", pkg: pkg, name: rest}
+	default:
+		module = moduleOf(pkg, modules)
+	}
+
+	if pkg == module {
+		return symbolPath{module: module, pkg: ".", name: rest}
+	}
+	return symbolPath{module: module, pkg: strings.TrimPrefix(pkg, module+"/"), name: rest}
+}
+
+// packagePath is path of package node, which is module node if symbol has no package.
+func (s symbolPath) packagePath() string {
+	if s.pkg == "" {
+		return s.module
+	}
+	return s.module + "/" + s.pkg
+}
+
+// path of symbol node.
+func (s symbolPath) path() string { return s.packagePath() + "/" + s.name }
+
+// moduleOf package is longest of modules that contains package, or package itself if there is none.
+func moduleOf(pkg string, modules []string) string {
+	var module string
+	for _, m := range modules {
+		if m != "" && (pkg == m || strings.HasPrefix(pkg, m+"/")) && len(m) > len(module) {
+			module = m
+		}
+	}
+	if module == "" {
+		return pkg
+	}
+	return module
+}
+
+// isStd is true for packages that are not in main package or modules and do not have domain.
+func isStd(pkg string, modules []string) bool {
+	if pkg == "main" {
+		return false
+	}
+	for _, m := range modules {
+		if m != "" && (pkg == m || strings.HasPrefix(pkg, m+"/")) {
+			return false
+		}
+	}
+	return !strings.Contains(strings.Split(pkg, "/")[0], ".")
+}
+
+// collapseTypeArgs replaces type arguments in brackets with `...`.
+func collapseTypeArgs(name string) string {
+	start := strings.Index(name, "[")
+	end := strings.LastIndex(name, "]")
+	if start < 0 || end < start {
+		return name
+	}
+	return name[:start] + "[...]" + name[end+1:]
+}
+
+// packageName is import path before first dot after last slash.
+// Linker generated symbols like `go:struct { ... }` or `type:*T` have no package.
+func packageName(name string) string {
+	prefix := name
+	if i := strings.IndexAny(prefix, "([{ "); i >= 0 {
+		prefix = prefix[:i]
+	}
+	if strings.Contains(prefix, ":") {
+		return ""
+	}
+	start := strings.LastIndex(prefix, "/") + 1
+	dot := strings.Index(prefix[start:], ".")
+	if dot <= 0 {
+		return ""
+	}
+	return prefix[:start+dot]
+}
@@ -0,0 +1,209 @@
+package covertreemap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image/color"
+	"sort"
+
+	"github.com/lucasb-eyer/go-colorful"
+	"golang.org/x/tools/cover"
+
+	"github.com/nikolaydubina/go-instrument-example/treemap"
+	"github.com/nikolaydubina/go-instrument-example/treemap/render"
+)
+
+// DiffStatus is how file changed between baseline and current profiles.
+type DiffStatus int
+
+const (
+	DiffStatusChanged DiffStatus = iota
+	DiffStatusAdded
+	DiffStatusRemoved
+)
+
+var (
+	DiffAddedColor   color.Color = colorful.Color{R: 0.6, G: 0.44, B: 0.67}
+	DiffRemovedColor color.Color = colorful.Color{R: 0.73, G: 0.73, B: 0.73}
+)
+
+// CoverageDiffTreemapBuilder creates single treemap tree where each leaf is a file.
+// Heat is change in test coverage from baseline to current, mapped to 0~1 where 0.5 is no change.
+// Size is number of statements in current profile, or in baseline profile for removed files.
+type CoverageDiffTreemapBuilder struct {
+	countStatements bool
+}
+
+// NewCoverageDiffTreemapBuilder is constructor.
+func NewCoverageDiffTreemapBuilder(
+	countStatements bool,
+) CoverageDiffTreemapBuilder {
+	return CoverageDiffTreemapBuilder{
+		countStatements: countStatements,
+	}
+}
+
+// coverageStats is number of statements in node and its children.
+type coverageStats struct {
+	baseCovered int64
+	baseTotal   int64
+	currCovered int64
+	currTotal   int64
+}
+
+// CoverageDiffTreemapFromProfiles from baseline and current profiles.
+// Heat of packages is computed from change of their total coverage, not from heat of children.
+// Returns status of each file that was added or removed.
+func (s CoverageDiffTreemapBuilder) CoverageDiffTreemapFromProfiles(ctx context.Context, base []*cover.Profile, curr []*cover.Profile) (*treemap.Tree, map[string]DiffStatus, error) {
+	if len(base) == 0 && len(curr) == 0 {
+		return nil, nil, errors.New("no profiles passed")
+	}
+
+	baseProfiles, err := profilesByFileName(base)
+	if err != nil {
+		return nil, nil, fmt.Errorf("bad baseline profiles: %w", err)
+	}
+	currProfiles, err := profilesByFileName(curr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("bad current profiles: %w", err)
+	}
+
+	tree := treemap.Tree{
+		Nodes: map[string]treemap.Node{},
+		To:    map[string][]string{},
+	}
+
+	// for finding roots
+	hasParent := map[string]bool{}
+
+	statuses := map[string]DiffStatus{}
+	stats := map[string]coverageStats{}
+
+	for _, fileName := range unionKeys(baseProfiles, currProfiles) {
+		baseProfile, inBase := baseProfiles[fileName]
+		currProfile, inCurr := currProfiles[fileName]
+
+		var stat coverageStats
+		if inBase {
+			stat.baseCovered, stat.baseTotal = coveredStatements(ctx, baseProfile)
+		}
+		if inCurr {
+			stat.currCovered, stat.currTotal = coveredStatements(ctx, currProfile)
+		}
+
+		size := stat.currTotal
+		switch {
+		case !inBase:
+			statuses[fileName] = DiffStatusAdded
+		case !inCurr:
+			statuses[fileName] = DiffStatusRemoved
+			size = stat.baseTotal
+		}
+		if !s.countStatements || size == 0 {
+			// fallback
+			size = 1
+		}
+
+		tree.Nodes[fileName] = treemap.Node{
+			Path: fileName,
+			Size: float64(size),
+		}
+		treemap.AddPath(&tree, hasParent, fileName)
+
+		for p := fileName; p != ""; p = parent(p) {
+			v := stats[p]
+			v.baseCovered += stat.baseCovered
+			v.baseTotal += stat.baseTotal
+			v.currCovered += stat.currCovered
+			v.currTotal += stat.currTotal
+			stats[p] = v
+		}
+	}
+
+	for path, stat := range stats {
+		node := tree.Nodes[path]
+		node.Heat = diffHeat(stat)
+		node.HasHeat = true
+		tree.Nodes[path] = node
+	}
+
+	if err := treemap.SetRoot(&tree, hasParent); err != nil {
+		return nil, nil, err
+	}
+
+	return &tree, statuses, nil
+}
+
+// diffHeat maps change of coverage in range -1~1 to 0~1.
+// If node has no statements in either baseline or current, then change is zero.
+func diffHeat(stat coverageStats) float64 {
+	if stat.baseTotal == 0 || stat.currTotal == 0 {
+		return 0.5
+	}
+	delta := float64(stat.currCovered)/float64(stat.currTotal) - float64(stat.baseCovered)/float64(stat.baseTotal)
+	return 0.5 + (delta / 2)
+}
+
+func profilesByFileName(profiles []*cover.Profile) (map[string]*cover.Profile, error) {
+	m := make(map[string]*cover.Profile, len(profiles))
+	for _, profile := range profiles {
+		if profile == nil {
+			return nil, errors.New("got nil profile")
+		}
+		if _, ok := m[profile.FileName]; ok {
+			return nil, fmt.Errorf("duplicate node(%s)", profile.FileName)
+		}
+		m[profile.FileName] = profile
+	}
+	return m, nil
+}
+
+// unionKeys returns sorted keys present in any of maps.
+func unionKeys(a, b map[string]*cover.Profile) []string {
+	keys := make([]string, 0, len(a))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// DiffColorer colors added and removed files with own colors.
+// Rest of nodes are colored by underlying colorer.
+type DiffColorer struct {
+	Colorer      render.Colorer
+	Statuses     map[string]DiffStatus
+	AddedColor   color.Color
+	RemovedColor color.Color
+}
+
+func (s DiffColorer) ColorBox(ctx context.Context, tree treemap.Tree, node string) color.Color {
+	switch s.Statuses[tree.Nodes[node].Path] {
+	case DiffStatusAdded:
+		return s.AddedColor
+	case DiffStatusRemoved:
+		return s.RemovedColor
+	default:
+		return s.Colorer.ColorBox(ctx, tree, node)
+	}
+}
+
+func (s DiffColorer) ColorText(ctx context.Context, tree treemap.Tree, node string) color.Color {
+	if s.Statuses[tree.Nodes[node].Path] == DiffStatusChanged {
+		return s.Colorer.ColorText(ctx, tree, node)
+	}
+	boxColor, _ := colorful.MakeColor(s.ColorBox(ctx, tree, node))
+	_, _, l := boxColor.Hcl()
+	switch {
+	case l > 0.5:
+		return render.DarkTextColor
+	default:
+		return render.LightTextColor
+	}
+}
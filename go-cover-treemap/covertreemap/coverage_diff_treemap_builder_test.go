@@ -0,0 +1,46 @@
+package covertreemap
+
+import (
+	"context"
+	"testing"
+
+	"golang.org/x/tools/cover"
+
+	"github.com/nikolaydubina/go-instrument-example/treemap"
+)
+
+func TestCoverageDiffTreemapPackageHeatAfterSizeImputer(t *testing.T) {
+	ctx := context.Background()
+
+	profile := func(fileName string, covered, total int) *cover.Profile {
+		return &cover.Profile{
+			FileName: fileName,
+			Blocks: []cover.ProfileBlock{
+				{NumStmt: covered, Count: 1},
+				{NumStmt: total - covered, Count: 0},
+			},
+		}
+	}
+	base := []*cover.Profile{
+		profile("example.com/a/pkg/x.go", 0, 10),
+		profile("example.com/a/pkg/y.go", 0, 10),
+	}
+	curr := []*cover.Profile{
+		profile("example.com/a/pkg/x.go", 10, 10),
+		profile("example.com/a/pkg/y.go", 10, 10),
+	}
+
+	tree, _, err := NewCoverageDiffTreemapBuilder(true).CoverageDiffTreemapFromProfiles(ctx, base, curr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	treemap.SumSizeImputer{EmptyLeafSize: 1}.ImputeSize(ctx, *tree)
+
+	node := tree.Nodes["example.com/a/pkg"]
+	if !node.HasHeat || node.Heat != 1 {
+		t.Errorf("package heat(%v) has heat(%v), expected 1", node.Heat, node.HasHeat)
+	}
+	if node.Size != 20 {
+		t.Errorf("package size(%v), expected 20", node.Size)
+	}
+}
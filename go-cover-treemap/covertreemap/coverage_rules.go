@@ -0,0 +1,146 @@
+package covertreemap
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"image/color"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lucasb-eyer/go-colorful"
+	"github.com/nikolaydubina/go-instrument-example/treemap"
+)
+
+// CoverageRule is minimum coverage of nodes which path matches pattern.
+type CoverageRule struct {
+	Pattern string
+	Min     float64
+	Line    int
+	match   treemap.PathPattern
+}
+
+// ParseCoverageRulesFromReader parses rules, one per line, like `internal/** >= 80%`.
+// Pattern is same as in path filter, glob or regexp with `re:` prefix.
+// Minimum is percent with `%` or fraction in 0~1.
+// Empty lines and lines starting with `#` are skipped.
+func ParseCoverageRulesFromReader(r io.Reader) ([]CoverageRule, error) {
+	var rules []CoverageRule
+
+	scanner := bufio.NewScanner(r)
+	for i := 1; scanner.Scan(); i++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		k := strings.LastIndex(line, ">=")
+		if k < 0 {
+			return nil, fmt.Errorf("no >= in line(%d)", i)
+		}
+		pattern := strings.TrimSpace(line[:k])
+		value := strings.TrimSpace(line[k+2:])
+
+		match, err := treemap.ParsePathPattern(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("bad pattern in line(%d): %w", i, err)
+		}
+
+		isPercent := strings.HasSuffix(value, "%")
+		minCoverage, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(value, "%")), 64)
+		if err != nil {
+			return nil, fmt.Errorf("minimum(%s) is not number in line(%d): %w", value, i, err)
+		}
+		if isPercent {
+			minCoverage /= 100
+		}
+		if minCoverage < 0 || minCoverage > 1 {
+			return nil, fmt.Errorf("minimum(%s) is out of range in line(%d)", value, i)
+		}
+
+		rules = append(rules, CoverageRule{Pattern: pattern, Min: minCoverage, Line: i, match: match})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("can not read rules: %w", err)
+	}
+
+	return rules, nil
+}
+
+// CoverageViolation is node which coverage is below minimum of its rule.
+type CoverageViolation struct {
+	Path     string  `json:"path"`
+	Coverage float64 `json:"coverage"`
+	Min      float64 `json:"min"`
+	Rule     string  `json:"rule"`
+	Line     int     `json:"line"`
+}
+
+// CoverageEvaluator checks coverage of nodes against rules.
+// Heat of nodes should be coverage, including imputed heat of parents.
+// Each node is checked against last rule that matches it, so that later rules can override earlier ones.
+type CoverageEvaluator struct {
+	Rules []CoverageRule
+}
+
+// Evaluate returns violations sorted by path.
+func (s CoverageEvaluator) Evaluate(ctx context.Context, tree treemap.Tree) []CoverageViolation {
+	var violations []CoverageViolation
+
+	for key, node := range tree.Nodes {
+		// artificial root of multiple roots is not part of code
+		if key == treemap.ArtificialRoot || !node.HasHeat {
+			continue
+		}
+
+		path := node.Path
+		if path == "" {
+			path = key
+		}
+
+		var rule *CoverageRule
+		for i := range s.Rules {
+			if s.Rules[i].match.Match(path) {
+				rule = &s.Rules[i]
+			}
+		}
+		if rule == nil || node.Heat >= rule.Min {
+			continue
+		}
+
+		violations = append(violations, CoverageViolation{
+			Path:     path,
+			Coverage: node.Heat,
+			Min:      rule.Min,
+			Rule:     rule.Pattern,
+			Line:     rule.Line,
+		})
+	}
+
+	sort.Slice(violations, func(i, j int) bool { return violations[i].Path < violations[j].Path })
+	return violations
+}
+
+var ViolationBorderColor color.Color = colorful.Color{R: 0.9, G: 0, B: 0}
+
+// ViolationBorderer highlights boxes of violations with thick border.
+type ViolationBorderer struct {
+	Violations []CoverageViolation
+	Color      color.Color
+	Width      float64
+}
+
+func (s ViolationBorderer) BorderBox(ctx context.Context, tree treemap.Tree, node string) (color.Color, float64, bool) {
+	path := node
+	if n, ok := tree.Nodes[node]; ok && n.Path != "" {
+		path = n.Path
+	}
+	for _, v := range s.Violations {
+		if v.Path == path {
+			return s.Color, s.Width, true
+		}
+	}
+	return nil, 0, false
+}
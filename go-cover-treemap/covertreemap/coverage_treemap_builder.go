@@ -4,7 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"strings"
+	"math"
 
 	"github.com/nikolaydubina/go-instrument-example/treemap"
 	"golang.org/x/tools/cover"
@@ -12,23 +12,82 @@ import (
 
 // CoverageTreemapBuilder creates single treemap tree where each leaf is a file.
 // Heat is test coverage.
-// Size is number of lines.
+// Size is from size metric, number of statements by default.
+// If sources are set, then each function is leaf under its file.
+// If grouped by modules, then levels are module, package and file.
 type CoverageTreemapBuilder struct {
-	countStatements bool
+	size         SizeMetric
+	sources      SourceFinder
+	filter       treemap.PathFilter
+	groupModules bool
+	modules      []string
+	hitsHeat     bool
 }
 
 // NewCoverageTreemapBuilder is constructor.
+// If countStatements, then size is number of statements, otherwise all files have same size.
 func NewCoverageTreemapBuilder(
 	countStatements bool,
 ) CoverageTreemapBuilder {
+	var size SizeMetric = FilesSize{}
+	if countStatements {
+		size = StatementsSize{}
+	}
 	return CoverageTreemapBuilder{
-		countStatements: countStatements,
+		size: size,
 	}
 }
 
+// WithSizeMetric sets size of files and functions.
+func (s CoverageTreemapBuilder) WithSizeMetric(size SizeMetric) CoverageTreemapBuilder {
+	s.size = size
+	return s
+}
+
+// WithFunctions makes builder parse sources of each file and add functions as leaves under file nodes.
+func (s CoverageTreemapBuilder) WithFunctions(sources SourceFinder) CoverageTreemapBuilder {
+	s.sources = sources
+	return s
+}
+
+// WithPathFilter makes builder skip profiles of files that are not kept by filter.
+// This is done before computing coverage, so excluded files do not affect coverage of parents.
+func (s CoverageTreemapBuilder) WithPathFilter(filter treemap.PathFilter) CoverageTreemapBuilder {
+	s.filter = filter
+	return s
+}
+
+// WithModules makes builder group files by module and package instead of splitting paths by "/".
+// Module of file is longest of given module paths, like one from go.mod, or it is inferred from path.
+// Module nodes are named by module path, and package nodes by directory within module.
+func (s CoverageTreemapBuilder) WithModules(modules ...string) CoverageTreemapBuilder {
+	s.groupModules = true
+	s.modules = modules
+	return s
+}
+
+// WithHitsHeat makes heat from execution frequency instead of percent of covered statements.
+// Heat is log of mean hits per statement, divided by maximum over all nodes.
+// This is useful for count and atomic modes, for set mode it is same as coverage.
+func (s CoverageTreemapBuilder) WithHitsHeat() CoverageTreemapBuilder {
+	s.hitsHeat = true
+	return s
+}
+
 // CoverageTreemapFromProfiles from profiles.
 // Note, we should not normalize heat since go coverage already reports 0~100%.
+// Heat of hits is normalized, since there is no upper bound on hits.
 func (s CoverageTreemapBuilder) CoverageTreemapFromProfiles(ctx context.Context, profiles []*cover.Profile) (*treemap.Tree, error) {
+	if !s.filter.IsEmpty() {
+		var kept []*cover.Profile
+		for _, profile := range profiles {
+			if profile != nil && !s.filter.Keep(profile.FileName) {
+				continue
+			}
+			kept = append(kept, profile)
+		}
+		profiles = kept
+	}
 	if len(profiles) == 0 {
 		return nil, errors.New("no profiles passed")
 	}
@@ -49,90 +108,192 @@ func (s CoverageTreemapBuilder) CoverageTreemapFromProfiles(ctx context.Context,
 			return nil, fmt.Errorf("duplicate node(%s)", profile.FileName)
 		}
 
-		var size int = 1
-		if s.countStatements {
-			size = numStatements(ctx, profile)
-			if size == 0 {
-				// fallback
-				size = 1
+		size, err := s.size.Size(ctx, profile)
+		if err != nil {
+			return nil, fmt.Errorf("can not get size of file(%s): %w", profile.FileName, err)
+		}
+		if size == 0 {
+			if _, ok := s.size.(zeroSizeSkipper); ok {
+				continue
 			}
+			// fallback
+			size = 1
 		}
 
-		parts := strings.Split(profile.FileName, "/")
-		hasParent[parts[0]] = false
-
 		tree.Nodes[profile.FileName] = treemap.Node{
 			Path:    profile.FileName,
-			Size:    float64(size),
-			Heat:    percentCovered(ctx, profile),
+			Size:    size,
+			Heat:    s.heat(ctx, profile),
 			HasHeat: true,
 		}
+		if s.groupModules {
+			addModuleParents(&tree, hasParent, profile.FileName, s.modules)
+		} else {
+			treemap.AddPath(&tree, hasParent, profile.FileName)
+		}
 
-		for parent, i := parts[0], 1; i < len(parts); i++ {
-			child := parent + "/" + parts[i]
-
-			tree.Nodes[parent] = treemap.Node{
-				Path: parent,
+		if s.sources != nil {
+			if err := s.addFunctions(ctx, &tree, profile); err != nil {
+				return nil, fmt.Errorf("can not add functions of file(%s): %w", profile.FileName, err)
 			}
+		}
+	}
 
-			tree.To[parent] = append(tree.To[parent], child)
-			hasParent[child] = true
+	if len(tree.Nodes) == 0 {
+		return nil, errors.New("no files with non zero size")
+	}
 
-			parent = child
-		}
+	if err := treemap.SetRoot(&tree, hasParent); err != nil {
+		return nil, err
+	}
+
+	if s.hitsHeat {
+		normalizeByMaxHeat(&tree)
 	}
 
-	for node, v := range tree.To {
-		tree.To[node] = unique(v)
+	return &tree, nil
+}
+
+// heat of profile, depending on mode of builder.
+func (s CoverageTreemapBuilder) heat(ctx context.Context, p *cover.Profile) float64 {
+	if s.hitsHeat {
+		return math.Log1p(meanHits(ctx, p))
 	}
+	return percentCovered(ctx, p)
+}
 
-	var roots []string
-	for node, has := range hasParent {
-		if !has {
-			roots = append(roots, node)
+// normalizeByMaxHeat scales heat into 0~1, so that zero stays zero.
+func normalizeByMaxHeat(tree *treemap.Tree) {
+	var maxHeat float64
+	for _, node := range tree.Nodes {
+		if node.HasHeat && node.Heat > maxHeat {
+			maxHeat = node.Heat
 		}
 	}
+	if maxHeat == 0 {
+		return
+	}
+	for path, node := range tree.Nodes {
+		if node.HasHeat {
+			node.Heat /= maxHeat
+			tree.Nodes[path] = node
+		}
+	}
+}
 
-	switch {
-	case len(roots) == 0:
-		return nil, errors.New("no roots, possible cycle in graph")
-	case len(roots) > 1:
-		tree.Root = "some-secret-string"
-		tree.To[tree.Root] = roots
-	default:
-		tree.Root = roots[0]
+// addModuleParents adds module and package nodes of file.
+// Package node path is module path and directory, with "." for root package,
+// so that it does not clash with module node.
+func addModuleParents(tree *treemap.Tree, hasParent map[string]bool, fileName string, modules []string) {
+	module, pkg, file := splitModulePath(fileName, modules)
+	pkgPath := module + "/" + pkg
+
+	tree.Nodes[module] = treemap.Node{
+		Path: module,
+		Name: module,
+	}
+	tree.Nodes[pkgPath] = treemap.Node{
+		Path: pkgPath,
+		Name: pkg,
 	}
+	node := tree.Nodes[fileName]
+	node.Name = file
+	tree.Nodes[fileName] = node
 
-	return &tree, nil
+	tree.To[module] = append(tree.To[module], pkgPath)
+	tree.To[pkgPath] = append(tree.To[pkgPath], fileName)
+
+	if _, ok := hasParent[module]; !ok {
+		hasParent[module] = false
+	}
+	hasParent[pkgPath] = true
+	hasParent[fileName] = true
 }
 
-func unique(a []string) []string {
-	u := map[string]bool{}
-	var b []string
-	for _, q := range a {
-		if _, ok := u[q]; !ok {
-			u[q] = true
-			b = append(b, q)
+// addFunctions adds functions of profile file as leaves under file node.
+// Functions without statements or with zero size are skipped.
+func (s CoverageTreemapBuilder) addFunctions(ctx context.Context, tree *treemap.Tree, profile *cover.Profile) error {
+	src, err := s.sources.ReadSource(ctx, profile.FileName)
+	if err != nil {
+		return fmt.Errorf("can not read source: %w", err)
+	}
+
+	funcs, err := findFuncs(ctx, profile.FileName, src)
+	if err != nil {
+		return fmt.Errorf("can not parse source: %w", err)
+	}
+
+	for _, f := range funcs {
+		fprofile := &cover.Profile{
+			FileName: profile.FileName,
+			Mode:     profile.Mode,
+			Blocks:   f.blocks(profile),
+		}
+
+		if numStatements(ctx, fprofile) == 0 {
+			continue
+		}
+
+		var size float64
+		if fs, ok := s.size.(functionSizer); ok {
+			size = fs.functionSize(f)
+		} else if size, err = s.size.Size(ctx, fprofile); err != nil {
+			return fmt.Errorf("can not get size of function(%s): %w", f.name, err)
+		}
+		if size == 0 {
+			continue
+		}
+
+		path := profile.FileName + "/" + f.name
+		if _, ok := tree.Nodes[path]; ok {
+			// same name can be declared multiple times, e.g. init
+			path = fmt.Sprintf("%s:%d", path, f.startLine)
 		}
+
+		tree.Nodes[path] = treemap.Node{
+			Path:    path,
+			Size:    size,
+			Heat:    s.heat(ctx, fprofile),
+			HasHeat: true,
+		}
+		tree.To[profile.FileName] = append(tree.To[profile.FileName], path)
 	}
-	return b
+
+	return nil
 }
 
 // This is based on official go tool.
 // Returns value in range 0~1
 // Official reference: https://github.com/golang/go/blob/master/src/cmd/cover/html.go#L97
 func percentCovered(ctx context.Context, p *cover.Profile) float64 {
-	var total, covered int64
+	covered, total := coveredStatements(ctx, p)
+	if total == 0 {
+		return 0
+	}
+	return float64(covered) / float64(total)
+}
+
+func coveredStatements(ctx context.Context, p *cover.Profile) (covered int64, total int64) {
 	for _, b := range p.Blocks {
 		total += int64(b.NumStmt)
 		if b.Count > 0 {
 			covered += int64(b.NumStmt)
 		}
 	}
+	return covered, total
+}
+
+// meanHits is number of executions per statement.
+func meanHits(ctx context.Context, p *cover.Profile) float64 {
+	var hits, total float64
+	for _, b := range p.Blocks {
+		hits += float64(b.Count) * float64(b.NumStmt)
+		total += float64(b.NumStmt)
+	}
 	if total == 0 {
 		return 0
 	}
-	return float64(covered) / float64(total)
+	return hits / total
 }
 
 func numStatements(ctx context.Context, p *cover.Profile) int {
@@ -0,0 +1,135 @@
+package covertreemap
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/cover"
+)
+
+// SourceFinder returns Go source of file referenced in coverage profile.
+type SourceFinder interface {
+	ReadSource(ctx context.Context, fileName string) ([]byte, error)
+}
+
+// GoBuildSourceFinder locates sources same way as `go tool cover -func`.
+// Profile file name is import path of package and file name.
+// It reads any file named in profile from local file system, so it is for command line use and not for profiles from untrusted users.
+type GoBuildSourceFinder struct{}
+
+func (s GoBuildSourceFinder) ReadSource(ctx context.Context, fileName string) ([]byte, error) {
+	if strings.HasPrefix(fileName, ".") || filepath.IsAbs(fileName) {
+		return os.ReadFile(fileName)
+	}
+	dir, file := path.Split(fileName)
+	pkg, err := build.Import(dir, ".", build.FindOnly)
+	if err != nil {
+		return nil, fmt.Errorf("can not find package(%s): %w", dir, err)
+	}
+	return os.ReadFile(filepath.Join(pkg.Dir, file))
+}
+
+// FSSourceFinder reads sources from file system.
+// ModulePath is trimmed from profile file name to get path within file system.
+type FSSourceFinder struct {
+	FS         fs.FS
+	ModulePath string
+}
+
+func (s FSSourceFinder) ReadSource(ctx context.Context, fileName string) ([]byte, error) {
+	name := fileName
+	if s.ModulePath != "" {
+		name = strings.TrimPrefix(strings.TrimPrefix(name, s.ModulePath), "/")
+	}
+	return fs.ReadFile(s.FS, name)
+}
+
+// funcExtent describes function position in source.
+// This is based on official go tool.
+// Official reference: https://github.com/golang/go/blob/master/src/cmd/cover/func.go
+type funcExtent struct {
+	name      string
+	startLine int
+	startCol  int
+	endLine   int
+	endCol    int
+}
+
+func findFuncs(ctx context.Context, fileName string, src []byte) ([]funcExtent, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, fileName, src, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var funcs []funcExtent
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			// do not count declarations of assembly functions
+			continue
+		}
+		start := fset.Position(fn.Pos())
+		end := fset.Position(fn.End())
+		funcs = append(funcs, funcExtent{
+			name:      funcName(fn),
+			startLine: start.Line,
+			startCol:  start.Column,
+			endLine:   end.Line,
+			endCol:    end.Column,
+		})
+	}
+	return funcs, nil
+}
+
+// funcName is name of function with receiver type if it is method, e.g. `(*T).Name`.
+func funcName(fn *ast.FuncDecl) string {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return fn.Name.Name
+	}
+	return "(" + recvName(fn.Recv.List[0].Type) + ")." + fn.Name.Name
+}
+
+func recvName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return "*" + recvName(t.X)
+	case *ast.ParenExpr:
+		return recvName(t.X)
+	case *ast.IndexExpr:
+		return recvName(t.X)
+	case *ast.IndexListExpr:
+		return recvName(t.X)
+	case *ast.Ident:
+		return t.Name
+	default:
+		return "?"
+	}
+}
+
+// blocks returns profile blocks that belong to function.
+// Blocks are sorted, so we can stop as soon as we reach the end of function.
+func (f funcExtent) blocks(p *cover.Profile) []cover.ProfileBlock {
+	var blocks []cover.ProfileBlock
+	for _, b := range p.Blocks {
+		if b.StartLine > f.endLine || (b.StartLine == f.endLine && b.StartCol >= f.endCol) {
+			// past the end of the function
+			break
+		}
+		if b.EndLine < f.startLine || (b.EndLine == f.startLine && b.EndCol <= f.startCol) {
+			// before the beginning of the function
+			continue
+		}
+		blocks = append(blocks, b)
+	}
+	return blocks
+}
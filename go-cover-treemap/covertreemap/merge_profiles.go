@@ -0,0 +1,133 @@
+package covertreemap
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/cover"
+)
+
+// MergeProfiles merges profiles of same file, like ones from sharded test runs.
+// Blocks at same position are merged, counts are summed in count and atomic modes and OR-ed in set mode.
+// All profiles should have same mode, and same block should have same number of statements.
+// Result is sorted by file name and blocks by position, inputs are not changed.
+func MergeProfiles(profiles []*cover.Profile) ([]*cover.Profile, error) {
+	byFile := map[string]*cover.Profile{}
+	var mode string
+
+	for _, p := range profiles {
+		if p == nil {
+			return nil, fmt.Errorf("got nil profile")
+		}
+		if mode == "" {
+			mode = p.Mode
+		}
+		if p.Mode != mode {
+			return nil, fmt.Errorf("can not merge mode(%s) of file(%s) with mode(%s)", p.Mode, p.FileName, mode)
+		}
+
+		merged, ok := byFile[p.FileName]
+		if !ok {
+			merged = &cover.Profile{FileName: p.FileName, Mode: p.Mode}
+			byFile[p.FileName] = merged
+		}
+		merged.Blocks = append(merged.Blocks, p.Blocks...)
+	}
+
+	result := make([]*cover.Profile, 0, len(byFile))
+	for _, p := range byFile {
+		blocks, err := mergeBlocks(p.Blocks, p.Mode)
+		if err != nil {
+			return nil, fmt.Errorf("can not merge file(%s): %w", p.FileName, err)
+		}
+		p.Blocks = blocks
+		result = append(result, p)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].FileName < result[j].FileName })
+
+	return result, nil
+}
+
+// mergeBlocks sorts blocks by position and merges blocks at same position.
+func mergeBlocks(blocks []cover.ProfileBlock, mode string) ([]cover.ProfileBlock, error) {
+	sort.SliceStable(blocks, func(i, j int) bool {
+		a, b := blocks[i], blocks[j]
+		if a.StartLine != b.StartLine {
+			return a.StartLine < b.StartLine
+		}
+		if a.StartCol != b.StartCol {
+			return a.StartCol < b.StartCol
+		}
+		if a.EndLine != b.EndLine {
+			return a.EndLine < b.EndLine
+		}
+		return a.EndCol < b.EndCol
+	})
+
+	var merged []cover.ProfileBlock
+	for _, b := range blocks {
+		if n := len(merged); n > 0 {
+			last := &merged[n-1]
+			if b.StartLine == last.StartLine && b.StartCol == last.StartCol && b.EndLine == last.EndLine && b.EndCol == last.EndCol {
+				if b.NumStmt != last.NumStmt {
+					return nil, fmt.Errorf("inconsistent number of statements(%d, %d) at line(%d)", last.NumStmt, b.NumStmt, b.StartLine)
+				}
+				if mode == "set" {
+					last.Count |= b.Count
+				} else {
+					last.Count += b.Count
+				}
+				continue
+			}
+		}
+		merged = append(merged, b)
+	}
+	return merged, nil
+}
+
+// ParseMergedProfilesFromReader parses one or more profiles concatenated together, each starting with mode line.
+func ParseMergedProfilesFromReader(r io.Reader) ([]*cover.Profile, error) {
+	var profiles []*cover.Profile
+	var section bytes.Buffer
+
+	flush := func() error {
+		if section.Len() == 0 {
+			return nil
+		}
+		p, err := cover.ParseProfilesFromReader(&section)
+		if err != nil {
+			return err
+		}
+		profiles = append(profiles, p...)
+		section.Reset()
+		return nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "mode: ") {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		}
+		section.WriteString(line)
+		section.WriteByte('\n')
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	return MergeProfiles(profiles)
+}
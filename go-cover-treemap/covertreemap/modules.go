@@ -0,0 +1,92 @@
+package covertreemap
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// ModulePathFromGoMod reads module path from contents of go.mod file.
+func ModulePathFromGoMod(data []byte) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		if !strings.HasPrefix(line, "module") {
+			continue
+		}
+		v := strings.TrimSpace(strings.TrimPrefix(line, "module"))
+		if v == line || v == "" {
+			continue
+		}
+		if unquoted, err := strconv.Unquote(v); err == nil {
+			v = unquoted
+		}
+		return v, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", errors.New("no module directive in go.mod")
+}
+
+// splitModulePath splits file name into module path, package directory relative to module and file.
+// Longest of known modules is used, otherwise module is inferred from package path.
+// Package directory is "." for root package of module.
+func splitModulePath(fileName string, modules []string) (module string, pkg string, file string) {
+	dir, file := path.Split(fileName)
+	dir = strings.TrimSuffix(dir, "/")
+
+	for _, m := range modules {
+		if (dir == m || strings.HasPrefix(dir, m+"/")) && len(m) > len(module) {
+			module = m
+		}
+	}
+	if module == "" {
+		module = inferModulePath(dir)
+	}
+
+	pkg = strings.TrimPrefix(strings.TrimPrefix(dir, module), "/")
+	if pkg == "" {
+		pkg = "."
+	}
+	return module, pkg, file
+}
+
+// inferModulePath guesses module path from package path.
+// Module of package with domain is host, owner and repository, like on GitHub.
+// Module of package without domain is its first element.
+// Major version suffix, like `/v5`, is part of module path.
+func inferModulePath(pkg string) string {
+	parts := strings.Split(pkg, "/")
+
+	n := 1
+	switch {
+	case parts[0] == "gopkg.in":
+		n = 2
+	case strings.Contains(parts[0], "."):
+		n = 3
+	}
+	if n > len(parts) {
+		n = len(parts)
+	}
+	if n < len(parts) && isMajorVersion(parts[n]) {
+		n++
+	}
+
+	return strings.Join(parts[:n], "/")
+}
+
+// isMajorVersion is true for suffixes like v2, v3 and so on.
+func isMajorVersion(s string) bool {
+	if len(s) < 2 || s[0] != 'v' {
+		return false
+	}
+	n, err := strconv.Atoi(s[1:])
+	return err == nil && n >= 2 && strconv.Itoa(n) == s[1:]
+}
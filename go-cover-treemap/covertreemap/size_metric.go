@@ -0,0 +1,82 @@
+package covertreemap
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"golang.org/x/tools/cover"
+)
+
+// SizeMetric gives size of file or function from its profile.
+type SizeMetric interface {
+	Size(ctx context.Context, p *cover.Profile) (float64, error)
+}
+
+// StatementsSize is number of statements.
+type StatementsSize struct{}
+
+func (s StatementsSize) Size(ctx context.Context, p *cover.Profile) (float64, error) {
+	return float64(numStatements(ctx, p)), nil
+}
+
+// UncoveredStatementsSize is number of statements that were not executed.
+// This shows where writing tests would add most coverage, so fully covered files are not in tree.
+type UncoveredStatementsSize struct{}
+
+func (s UncoveredStatementsSize) Size(ctx context.Context, p *cover.Profile) (float64, error) {
+	covered, total := coveredStatements(ctx, p)
+	return float64(total - covered), nil
+}
+
+func (s UncoveredStatementsSize) skipZeroSize() {}
+
+// BlocksSize is number of basic blocks.
+type BlocksSize struct{}
+
+func (s BlocksSize) Size(ctx context.Context, p *cover.Profile) (float64, error) {
+	return float64(len(p.Blocks)), nil
+}
+
+// FilesSize is same size for each file and function.
+type FilesSize struct{}
+
+func (s FilesSize) Size(ctx context.Context, p *cover.Profile) (float64, error) {
+	return 1, nil
+}
+
+// LinesSize is number of lines in source of file.
+// Functions are sized by lines from declaration to closing brace.
+type LinesSize struct {
+	Sources SourceFinder
+}
+
+func (s LinesSize) Size(ctx context.Context, p *cover.Profile) (float64, error) {
+	if s.Sources == nil {
+		return 0, errors.New("no sources")
+	}
+	src, err := s.Sources.ReadSource(ctx, p.FileName)
+	if err != nil {
+		return 0, fmt.Errorf("can not read source: %w", err)
+	}
+	n := bytes.Count(src, []byte("\n"))
+	if len(src) > 0 && src[len(src)-1] != '\n' {
+		n++
+	}
+	return float64(n), nil
+}
+
+func (s LinesSize) functionSize(f funcExtent) float64 {
+	return float64(f.endLine - f.startLine + 1)
+}
+
+// zeroSizeSkipper is metric where files of zero size are not in tree, other metrics show them with size 1.
+type zeroSizeSkipper interface {
+	skipZeroSize()
+}
+
+// functionSizer is metric that sizes functions by their position in source instead of their blocks.
+type functionSizer interface {
+	functionSize(f funcExtent) float64
+}
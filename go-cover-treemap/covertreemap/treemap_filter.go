@@ -98,3 +98,10 @@ func parent(path string) string {
 	}
 	return strings.Join(parts[:len(parts)-1], "/")
 }
+
+// RegisterTransforms adds filters of this package to registry.
+func RegisterTransforms(transforms *treemap.Transforms) {
+	transforms.RegisterFunc("remove-go-files", RemoveGoFilesTreemapFilter)
+	transforms.RegisterFunc("aggregate-go-files", AggregateGoFilesTreemapFilter)
+	transforms.RegisterFunc("collapse-roots-without-name", CollapseRootsWithoutNameTreemapFilter)
+}
@@ -0,0 +1,120 @@
+package fstreemap
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// MaxArchiveSize is largest size of tar archive in bytes after decompression, larger archives are rejected.
+// Whole archive is kept in memory, and gzip can compress by factor of 1000, so small upload can take all memory.
+const MaxArchiveSize = 512 << 20
+
+// ReadTarFS reads tar archive, possibly gzipped, into memory.
+// Only regular files and directories are kept, links are skipped.
+// Entries with paths outside of archive root are error.
+func ReadTarFS(r io.Reader) (fs.FS, error) {
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("can not read gzip: %w", err)
+		}
+		defer gz.Close()
+		r = gz
+	} else {
+		r = br
+	}
+
+	limited := &io.LimitedReader{R: r, N: MaxArchiveSize + 1}
+	errTooLarge := fmt.Errorf("archive is larger than %d bytes", MaxArchiveSize)
+
+	fsys := newArchiveFS()
+
+	tr := tar.NewReader(limited)
+	for {
+		hdr, err := tr.Next()
+		if limited.N <= 0 {
+			return nil, errTooLarge
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("can not read tar: %w", err)
+		}
+
+		name := path.Clean(strings.TrimPrefix(hdr.Name, "./"))
+		if name == "." {
+			continue
+		}
+		if !fs.ValidPath(name) {
+			return nil, fmt.Errorf("bad path(%s) in archive", hdr.Name)
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			fsys.add(name, archiveFile{
+				name:    path.Base(name),
+				mode:    fs.ModeDir | fs.FileMode(hdr.Mode).Perm(),
+				modTime: hdr.ModTime,
+			})
+		case tar.TypeReg:
+			if hdr.Size > limited.N {
+				return nil, errTooLarge
+			}
+			data := make([]byte, hdr.Size)
+			if _, err := io.ReadFull(tr, data); err != nil {
+				return nil, fmt.Errorf("can not read file(%s) from tar: %w", name, err)
+			}
+			fsys.add(name, archiveFile{
+				name:    path.Base(name),
+				data:    data,
+				mode:    fs.FileMode(hdr.Mode).Perm(),
+				modTime: hdr.ModTime,
+			})
+		}
+	}
+
+	if len(fsys.files) == 1 {
+		return nil, errors.New("empty archive")
+	}
+	return fsys, nil
+}
+
+// ParseChurnFromReader reads lines of count and path, like output of `uniq -c`.
+// For example, `git log --format= --name-only | sort | uniq -c`.
+// Counts of same path are summed.
+func ParseChurnFromReader(r io.Reader) (map[string]int, error) {
+	changes := map[string]int{}
+
+	scanner := bufio.NewScanner(r)
+	for i := 1; scanner.Scan(); i++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		count, name, ok := strings.Cut(line, " ")
+		if !ok {
+			return nil, fmt.Errorf("no path in line(%d)", i)
+		}
+		n, err := strconv.Atoi(count)
+		if err != nil {
+			return nil, fmt.Errorf("count(%s) is not integer in line(%d): %w", count, i, err)
+		}
+		changes[strings.TrimSpace(name)] += n
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("can not read: %w", err)
+	}
+
+	return changes, nil
+}
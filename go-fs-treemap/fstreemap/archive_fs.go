@@ -0,0 +1,152 @@
+package fstreemap
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"time"
+)
+
+// archiveFS is read only file system of files read from archive into memory.
+// Directories of files are present even if archive does not have entries for them.
+type archiveFS struct {
+	files    map[string]*archiveFile
+	children map[string][]string
+}
+
+func newArchiveFS() *archiveFS {
+	return &archiveFS{
+		files:    map[string]*archiveFile{".": {name: ".", mode: fs.ModeDir | 0o555}},
+		children: map[string][]string{},
+	}
+}
+
+// add file or directory, and its parent directories if they are not present.
+func (s *archiveFS) add(name string, f archiveFile) {
+	if existing, ok := s.files[name]; ok {
+		*existing = f
+		return
+	}
+	s.files[name] = &f
+
+	dir := path.Dir(name)
+	s.children[dir] = append(s.children[dir], name)
+	if _, ok := s.files[dir]; !ok {
+		s.add(dir, archiveFile{name: path.Base(dir), mode: fs.ModeDir | 0o555})
+	}
+}
+
+func (s *archiveFS) file(op, name string) (*archiveFile, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	f, ok := s.files[name]
+	if !ok {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	return f, nil
+}
+
+func (s *archiveFS) Open(name string) (fs.File, error) {
+	f, err := s.file("open", name)
+	if err != nil {
+		return nil, err
+	}
+	if f.IsDir() {
+		entries, err := s.ReadDir(name)
+		if err != nil {
+			return nil, err
+		}
+		return &archiveDir{archiveFile: f, entries: entries}, nil
+	}
+	return &archiveOpenFile{archiveFile: f, Reader: bytes.NewReader(f.data)}, nil
+}
+
+func (s *archiveFS) Stat(name string) (fs.FileInfo, error) {
+	return s.file("stat", name)
+}
+
+func (s *archiveFS) ReadFile(name string) ([]byte, error) {
+	f, err := s.file("read", name)
+	if err != nil {
+		return nil, err
+	}
+	if f.IsDir() {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrInvalid}
+	}
+	return append([]byte(nil), f.data...), nil
+}
+
+// ReadDir returns entries sorted by name.
+func (s *archiveFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	f, err := s.file("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	if !f.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	entries := make([]fs.DirEntry, 0, len(s.children[name]))
+	for _, child := range s.children[name] {
+		entries = append(entries, fs.FileInfoToDirEntry(s.files[child]))
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+// archiveFile is file or directory, it is its own file info.
+type archiveFile struct {
+	name    string
+	data    []byte
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func (f *archiveFile) Name() string       { return f.name }
+func (f *archiveFile) Size() int64        { return int64(len(f.data)) }
+func (f *archiveFile) Mode() fs.FileMode  { return f.mode }
+func (f *archiveFile) ModTime() time.Time { return f.modTime }
+func (f *archiveFile) IsDir() bool        { return f.mode.IsDir() }
+func (f *archiveFile) Sys() interface{}   { return nil }
+
+type archiveOpenFile struct {
+	*archiveFile
+	*bytes.Reader
+}
+
+func (f *archiveOpenFile) Stat() (fs.FileInfo, error) { return f.archiveFile, nil }
+func (f *archiveOpenFile) Close() error               { return nil }
+
+// Size is size of file, not unread part of it.
+func (f *archiveOpenFile) Size() int64 { return f.archiveFile.Size() }
+
+type archiveDir struct {
+	*archiveFile
+	entries []fs.DirEntry
+}
+
+func (d *archiveDir) Stat() (fs.FileInfo, error) { return d.archiveFile, nil }
+func (d *archiveDir) Close() error               { return nil }
+
+func (d *archiveDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: fs.ErrInvalid}
+}
+
+func (d *archiveDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if n <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(d.entries) {
+		n = len(d.entries)
+	}
+	entries := d.entries[:n]
+	d.entries = d.entries[n:]
+	return entries, nil
+}
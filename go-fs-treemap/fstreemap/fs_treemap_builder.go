@@ -0,0 +1,186 @@
+package fstreemap
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"time"
+
+	"github.com/nikolaydubina/go-instrument-example/treemap"
+)
+
+// HeatMetric gives heat of single file.
+// Values can be in any range, they are normalized to 0~1 over all files.
+// If file has no value, then false is returned.
+type HeatMetric interface {
+	Heat(ctx context.Context, name string, info fs.FileInfo) (float64, bool)
+}
+
+// AgeHeat is time since last modification in seconds.
+// Files that changed recently have low heat.
+type AgeHeat struct {
+	Now time.Time
+}
+
+func (s AgeHeat) Heat(ctx context.Context, name string, info fs.FileInfo) (float64, bool) {
+	if info.ModTime().IsZero() {
+		return 0, false
+	}
+	age := s.Now.Sub(info.ModTime()).Seconds()
+	if age < 0 {
+		age = 0
+	}
+	return age, true
+}
+
+// ChurnHeat is from number of changes of each file, like commits that touched it.
+// Heat is 1/(1+changes), so files that change often have low heat.
+// Files that are not in map did not change.
+type ChurnHeat struct {
+	Changes map[string]int
+}
+
+func (s ChurnHeat) Heat(ctx context.Context, name string, info fs.FileInfo) (float64, bool) {
+	return 1 / (1 + float64(s.Changes[name])), true
+}
+
+// FSTreemapBuilder creates single treemap tree where each leaf is file.
+// Path is path of file in file system.
+// Size is bytes or lines.
+// Directories are not sized, so that they can be imputed from files.
+// Empty directories and .git directories are not in tree.
+type FSTreemapBuilder struct {
+	countLines bool
+	heat       HeatMetric
+	ignore     *Ignore
+}
+
+// NewFSTreemapBuilder is constructor, if countLines then size is number of lines, otherwise bytes.
+func NewFSTreemapBuilder(countLines bool) FSTreemapBuilder {
+	return FSTreemapBuilder{
+		countLines: countLines,
+	}
+}
+
+// WithHeat sets heat of files from metric.
+func (s FSTreemapBuilder) WithHeat(heat HeatMetric) FSTreemapBuilder {
+	s.heat = heat
+	return s
+}
+
+// WithIgnore excludes files and directories.
+// Rules from .gitignore files found while walking are added to these rules.
+func (s FSTreemapBuilder) WithIgnore(ignore *Ignore) FSTreemapBuilder {
+	s.ignore = ignore
+	return s
+}
+
+// FSTreemap walks file system starting from root directory.
+func (s FSTreemapBuilder) FSTreemap(ctx context.Context, fsys fs.FS, root string) (*treemap.Tree, error) {
+	tree := treemap.Tree{
+		Nodes: map[string]treemap.Node{},
+		To:    map[string][]string{},
+	}
+
+	// rules are added while walking, so this is copy
+	ignore := &Ignore{}
+	if s.ignore != nil {
+		ignore.rules = append(ignore.rules, s.ignore.rules...)
+	}
+
+	var files []string
+	err := fs.WalkDir(fsys, root, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if name != root && ignore.Match(name, d.IsDir()) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		if d.IsDir() {
+			// repository metadata is never part of tree
+			if d.Name() == ".git" {
+				return fs.SkipDir
+			}
+			if err := addIgnoreFile(fsys, ignore, name); err != nil {
+				return err
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return fmt.Errorf("can not get info of file(%s): %w", name, err)
+		}
+
+		node := treemap.Node{
+			Path: name,
+			Size: float64(info.Size()),
+		}
+		if s.countLines {
+			data, err := fs.ReadFile(fsys, name)
+			if err != nil {
+				return fmt.Errorf("can not read file(%s): %w", name, err)
+			}
+			node.Size = float64(countLines(data))
+		}
+		if s.heat != nil {
+			node.Heat, node.HasHeat = s.heat.Heat(ctx, name, info)
+		}
+
+		tree.Nodes[name] = node
+		files = append(files, name)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("can not walk: %w", err)
+	}
+	if len(files) == 0 {
+		return nil, errors.New("no files")
+	}
+
+	// for finding roots
+	hasParent := map[string]bool{}
+
+	for _, name := range files {
+		treemap.AddPath(&tree, hasParent, name)
+	}
+
+	if err := treemap.SetRoot(&tree, hasParent); err != nil {
+		return nil, err
+	}
+
+	tree.NormalizeHeat(ctx)
+
+	return &tree, nil
+}
+
+// addIgnoreFile adds rules from .gitignore in directory, if there is one.
+func addIgnoreFile(fsys fs.FS, ignore *Ignore, dir string) error {
+	data, err := fs.ReadFile(fsys, path.Join(dir, ".gitignore"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("can not read .gitignore in dir(%s): %w", dir, err)
+	}
+	return ignore.addFromReader(dir, bytes.NewReader(data))
+}
+
+// countLines is number of lines, last line may be without newline.
+func countLines(data []byte) int {
+	n := bytes.Count(data, []byte("\n"))
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		n++
+	}
+	return n
+}
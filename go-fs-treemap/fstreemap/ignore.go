@@ -0,0 +1,113 @@
+package fstreemap
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/nikolaydubina/go-instrument-example/treemap"
+)
+
+// ignoreRule is single line of gitignore file.
+// Pattern is relative to directory of file where rule is defined.
+type ignoreRule struct {
+	dir     string
+	pattern string
+	negate  bool
+	dirOnly bool
+}
+
+// Ignore is list of gitignore-style rules, last matching rule wins.
+// Supported are comments, `!` negation, trailing `/` for directories,
+// leading or middle `/` for anchoring and `*`, `?`, `[...]` and `**` wildcards.
+// Like in git, file can not be included back if its directory is excluded.
+type Ignore struct {
+	rules []ignoreRule
+}
+
+// NewIgnore makes rules from patterns relative to root.
+func NewIgnore(patterns ...string) *Ignore {
+	var s Ignore
+	for _, p := range patterns {
+		s.add("", p)
+	}
+	return &s
+}
+
+// ParseIgnoreFromReader reads gitignore file located in directory dir.
+func ParseIgnoreFromReader(dir string, r io.Reader) (*Ignore, error) {
+	var s Ignore
+	if err := s.addFromReader(dir, r); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (s *Ignore) addFromReader(dir string, r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		s.add(dir, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("can not read ignore file: %w", err)
+	}
+	return nil
+}
+
+func (s *Ignore) add(dir string, line string) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return
+	}
+
+	rule := ignoreRule{dir: dir}
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	}
+	// escaped leading characters
+	if strings.HasPrefix(line, `\#`) || strings.HasPrefix(line, `\!`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return
+	}
+
+	// pattern without slash matches at any depth
+	if strings.Contains(line, "/") {
+		rule.pattern = strings.TrimPrefix(line, "/")
+	} else {
+		rule.pattern = "**/" + line
+	}
+
+	s.rules = append(s.rules, rule)
+}
+
+// Match reports whether slash separated path is excluded.
+func (s *Ignore) Match(name string, isDir bool) bool {
+	if s == nil {
+		return false
+	}
+	ignored := false
+	for _, rule := range s.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		rel := name
+		if rule.dir != "" && rule.dir != "." {
+			if !strings.HasPrefix(name, rule.dir+"/") {
+				continue
+			}
+			rel = strings.TrimPrefix(name, rule.dir+"/")
+		}
+		if treemap.MatchGlob(rule.pattern, rel) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
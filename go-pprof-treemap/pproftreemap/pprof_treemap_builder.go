@@ -0,0 +1,174 @@
+package pproftreemap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/nikolaydubina/go-instrument-example/treemap"
+)
+
+// PprofTreemapBuilder creates single treemap tree where each leaf is a function.
+// Path is package, file and function.
+// Size is flat value of function.
+// Heat is optional, it is ratio of cumulative to flat value mapped to 0~1 as 1 - flat/cum.
+// Heat is 0 when function spends all time in itself and goes to 1 when it spends most of time in callees.
+// Functions without flat value are not in tree.
+type PprofTreemapBuilder struct {
+	sampleType string
+	withHeat   bool
+}
+
+// NewPprofTreemapBuilder is constructor.
+// If sample type is empty, then default sample type of profile is used, or last one if there is no default.
+func NewPprofTreemapBuilder(
+	sampleType string,
+	withHeat bool,
+) PprofTreemapBuilder {
+	return PprofTreemapBuilder{
+		sampleType: sampleType,
+		withHeat:   withHeat,
+	}
+}
+
+// PprofTreemapFromProfile folds samples of profile.
+func (s PprofTreemapBuilder) PprofTreemapFromProfile(ctx context.Context, p *Profile) (*treemap.Tree, error) {
+	if p == nil {
+		return nil, errors.New("got nil profile")
+	}
+
+	idx, err := s.sampleIndex(p)
+	if err != nil {
+		return nil, err
+	}
+
+	flat := map[string]int64{}
+	cum := map[string]int64{}
+	var paths []string
+
+	for _, sample := range p.Samples {
+		if idx >= len(sample.Values) {
+			return nil, errors.New("sample has less values than sample types")
+		}
+		v := sample.Values[idx]
+		if v <= 0 {
+			continue
+		}
+
+		seen := map[string]bool{}
+		for i, locationID := range sample.LocationIDs {
+			location, ok := p.Locations[locationID]
+			if !ok {
+				return nil, fmt.Errorf("no location(%d)", locationID)
+			}
+			for j, line := range location.Lines {
+				fn, ok := p.Functions[line.FunctionID]
+				if !ok {
+					return nil, fmt.Errorf("no function(%d)", line.FunctionID)
+				}
+				path := functionPath(fn)
+
+				if i == 0 && j == 0 {
+					if _, ok := flat[path]; !ok {
+						paths = append(paths, path)
+					}
+					flat[path] += v
+				}
+
+				// recursive functions are counted once
+				if !seen[path] {
+					seen[path] = true
+					cum[path] += v
+				}
+			}
+		}
+	}
+
+	if len(paths) == 0 {
+		return nil, errors.New("no samples with values")
+	}
+
+	tree := treemap.Tree{
+		Nodes: map[string]treemap.Node{},
+		To:    map[string][]string{},
+	}
+
+	// for finding roots
+	hasParent := map[string]bool{}
+
+	for _, path := range paths {
+		node := treemap.Node{
+			Path: path,
+			Size: float64(flat[path]),
+		}
+		if s.withHeat {
+			node.Heat = 1 - float64(flat[path])/float64(cum[path])
+			node.HasHeat = true
+		}
+		tree.Nodes[path] = node
+	}
+
+	for _, path := range paths {
+		treemap.AddPath(&tree, hasParent, path)
+	}
+
+	if err := treemap.SetRoot(&tree, hasParent); err != nil {
+		return nil, err
+	}
+
+	return &tree, nil
+}
+
+func (s PprofTreemapBuilder) sampleIndex(p *Profile) (int, error) {
+	if len(p.SampleTypes) == 0 {
+		return 0, errors.New("no sample types")
+	}
+
+	sampleType := s.sampleType
+	if sampleType == "" {
+		sampleType = p.DefaultSampleType
+	}
+	if sampleType == "" {
+		return len(p.SampleTypes) - 1, nil
+	}
+
+	var types []string
+	for i, t := range p.SampleTypes {
+		if t.Type == sampleType {
+			return i, nil
+		}
+		types = append(types, t.Type)
+	}
+	return 0, fmt.Errorf("no sample type(%s), expected one of %v", sampleType, types)
+}
+
+// functionPath is package, file and function name joined by "/".
+func functionPath(fn Function) string {
+	pkg, name := splitFunctionName(fn.Name)
+	file := path.Base(fn.FileName)
+	if fn.FileName == "" {
+		file = "?"
+	}
+	if pkg == "" {
+		// e.g. assembly functions
+		return file + "/" + name
+	}
+	return pkg + "/" + file + "/" + name
+}
+
+// splitFunctionName splits full function name, like `github.com/a/b.(*T).Method`, into package and rest.
+// Type parameters can contain `/` and `.`, so they are not considered.
+func splitFunctionName(name string) (pkg string, fn string) {
+	head := name
+	if i := strings.Index(head, "["); i >= 0 {
+		head = head[:i]
+	}
+	start := strings.LastIndex(head, "/") + 1
+	i := strings.Index(head[start:], ".")
+	if i < 0 {
+		return "", name
+	}
+	return name[:start+i], name[start+i+1:]
+}
@@ -0,0 +1,272 @@
+package pproftreemap
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Profile is subset of pprof profile that is needed to fold samples.
+// Official reference: https://github.com/google/pprof/blob/main/proto/profile.proto
+type Profile struct {
+	SampleTypes       []ValueType
+	Samples           []Sample
+	Locations         map[uint64]Location
+	Functions         map[uint64]Function
+	DefaultSampleType string
+}
+
+type ValueType struct {
+	Type string
+	Unit string
+}
+
+// Sample has locations starting from leaf.
+type Sample struct {
+	LocationIDs []uint64
+	Values      []int64
+}
+
+// Location has lines starting from innermost inlined function.
+type Location struct {
+	ID    uint64
+	Lines []Line
+}
+
+type Line struct {
+	FunctionID uint64
+	Line       int64
+}
+
+type Function struct {
+	ID       uint64
+	Name     string
+	FileName string
+}
+
+// rawProfile has strings as indexes in string table.
+type rawProfile struct {
+	sampleTypes       [][2]int64
+	samples           []Sample
+	locations         []Location
+	functions         [][3]uint64 // id, name, filename
+	strings           []string
+	defaultSampleType int64
+}
+
+// MaxProfileSize is largest size of decompressed profile in bytes, larger profiles are rejected.
+// Gzip can compress by factor of 1000, so without limit small upload can take all memory.
+const MaxProfileSize = 256 << 20
+
+// ParseProfileFromReader parses pprof profile in protobuf format, possibly gzipped.
+func ParseProfileFromReader(in io.Reader) (*Profile, error) {
+	data, err := io.ReadAll(in)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) >= 2 && data[0] == 0x1f && data[1] == 0x8b {
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("can not decompress: %w", err)
+		}
+		if data, err = io.ReadAll(io.LimitReader(gz, MaxProfileSize+1)); err != nil {
+			return nil, fmt.Errorf("can not decompress: %w", err)
+		}
+		if len(data) > MaxProfileSize {
+			return nil, fmt.Errorf("decompressed profile is larger than %d bytes", MaxProfileSize)
+		}
+	}
+
+	var raw rawProfile
+	if err := raw.unmarshal(data); err != nil {
+		return nil, fmt.Errorf("can not parse: %w", err)
+	}
+
+	return raw.profile()
+}
+
+func (raw rawProfile) profile() (*Profile, error) {
+	str := func(i int64) (string, error) {
+		if i < 0 || i >= int64(len(raw.strings)) {
+			return "", fmt.Errorf("string index(%d) out of range", i)
+		}
+		return raw.strings[i], nil
+	}
+
+	p := Profile{
+		Samples:   raw.samples,
+		Locations: make(map[uint64]Location, len(raw.locations)),
+		Functions: make(map[uint64]Function, len(raw.functions)),
+	}
+
+	for _, v := range raw.sampleTypes {
+		t, err := str(v[0])
+		if err != nil {
+			return nil, err
+		}
+		u, err := str(v[1])
+		if err != nil {
+			return nil, err
+		}
+		p.SampleTypes = append(p.SampleTypes, ValueType{Type: t, Unit: u})
+	}
+
+	for _, loc := range raw.locations {
+		p.Locations[loc.ID] = loc
+	}
+
+	for _, v := range raw.functions {
+		name, err := str(int64(v[1]))
+		if err != nil {
+			return nil, err
+		}
+		fileName, err := str(int64(v[2]))
+		if err != nil {
+			return nil, err
+		}
+		p.Functions[v[0]] = Function{ID: v[0], Name: name, FileName: fileName}
+	}
+
+	if raw.defaultSampleType != 0 {
+		t, err := str(raw.defaultSampleType)
+		if err != nil {
+			return nil, err
+		}
+		p.DefaultSampleType = t
+	}
+
+	return &p, nil
+}
+
+func (raw *rawProfile) unmarshal(b []byte) error {
+	return walkFields(b, func(num protowire.Number, typ protowire.Type, v []byte, x uint64) error {
+		switch num {
+		case 1:
+			var vt [2]int64
+			err := walkFields(v, func(num protowire.Number, typ protowire.Type, v []byte, x uint64) error {
+				if num == 1 || num == 2 {
+					vt[num-1] = int64(x)
+				}
+				return nil
+			})
+			raw.sampleTypes = append(raw.sampleTypes, vt)
+			return err
+		case 2:
+			var s Sample
+			err := walkFields(v, func(num protowire.Number, typ protowire.Type, v []byte, x uint64) error {
+				switch num {
+				case 1:
+					return appendVarints(typ, v, x, func(x uint64) { s.LocationIDs = append(s.LocationIDs, x) })
+				case 2:
+					return appendVarints(typ, v, x, func(x uint64) { s.Values = append(s.Values, int64(x)) })
+				}
+				return nil
+			})
+			raw.samples = append(raw.samples, s)
+			return err
+		case 4:
+			var loc Location
+			err := walkFields(v, func(num protowire.Number, typ protowire.Type, v []byte, x uint64) error {
+				switch num {
+				case 1:
+					loc.ID = x
+				case 4:
+					var line Line
+					err := walkFields(v, func(num protowire.Number, typ protowire.Type, v []byte, x uint64) error {
+						switch num {
+						case 1:
+							line.FunctionID = x
+						case 2:
+							line.Line = int64(x)
+						}
+						return nil
+					})
+					loc.Lines = append(loc.Lines, line)
+					return err
+				}
+				return nil
+			})
+			raw.locations = append(raw.locations, loc)
+			return err
+		case 5:
+			var fn [3]uint64
+			err := walkFields(v, func(num protowire.Number, typ protowire.Type, v []byte, x uint64) error {
+				switch num {
+				case 1:
+					fn[0] = x
+				case 2:
+					fn[1] = x
+				case 4:
+					fn[2] = x
+				}
+				return nil
+			})
+			raw.functions = append(raw.functions, fn)
+			return err
+		case 6:
+			raw.strings = append(raw.strings, string(v))
+		case 14:
+			raw.defaultSampleType = int64(x)
+		}
+		return nil
+	})
+}
+
+// walkFields calls f for each field, with bytes for length-delimited fields and number for varint and fixed fields.
+func walkFields(b []byte, f func(num protowire.Number, typ protowire.Type, v []byte, x uint64) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		var v []byte
+		var x uint64
+		switch typ {
+		case protowire.VarintType:
+			x, n = protowire.ConsumeVarint(b)
+		case protowire.Fixed32Type:
+			var x32 uint32
+			x32, n = protowire.ConsumeFixed32(b)
+			x = uint64(x32)
+		case protowire.Fixed64Type:
+			x, n = protowire.ConsumeFixed64(b)
+		case protowire.BytesType:
+			v, n = protowire.ConsumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		if err := f(num, typ, v, x); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// appendVarints handles both packed and not packed repeated varint fields.
+func appendVarints(typ protowire.Type, v []byte, x uint64, add func(x uint64)) error {
+	if typ != protowire.BytesType {
+		add(x)
+		return nil
+	}
+	for len(v) > 0 {
+		x, n := protowire.ConsumeVarint(v)
+		if n < 0 {
+			return errors.New("bad packed varint")
+		}
+		add(x)
+		v = v[n:]
+	}
+	return nil
+}
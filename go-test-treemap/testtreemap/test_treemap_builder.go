@@ -0,0 +1,166 @@
+package testtreemap
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/nikolaydubina/go-instrument-example/treemap"
+)
+
+// TestEvent is single event from `go test -json` output.
+// Official reference: https://pkg.go.dev/cmd/test2json
+type TestEvent struct {
+	Time    time.Time
+	Action  string
+	Package string
+	Test    string
+	Elapsed float64
+	Output  string
+}
+
+// ParseTestEventsFromReader parses `go test -json` event stream.
+// Lines that are not JSON objects, like build errors, are skipped.
+func ParseTestEventsFromReader(in io.Reader) ([]TestEvent, error) {
+	var events []TestEvent
+
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(nil, 1<<20)
+	for i := 1; scanner.Scan(); i++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 || line[0] != '{' {
+			continue
+		}
+
+		var event TestEvent
+		if err := json.Unmarshal(line, &event); err != nil {
+			return nil, fmt.Errorf("can not parse line(%d): %w", i, err)
+		}
+		events = append(events, event)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// minElapsed is size of tests that are faster than resolution of elapsed time, which is 0.01s.
+// It is small, so that many fast tests do not outweigh slow ones, and not zero, so that they are still seen.
+const minElapsed = 0.001
+
+// testStats is outcomes of all runs of single test or package.
+type testStats struct {
+	elapsed float64
+	pass    int
+	fail    int
+	skip    int
+}
+
+func (s testStats) runs() int { return s.pass + s.fail + s.skip }
+
+// heat is 1 when all runs pass, 0 when all fail and 0.5 for skip.
+// Flaky tests are in between.
+func (s testStats) heat() float64 {
+	return (float64(s.pass) + 0.5*float64(s.skip)) / float64(s.runs())
+}
+
+// TestTreemapBuilder creates single treemap tree where each leaf is a test.
+// Path is package and test name, subtests are under their parent tests.
+// Heat is pass rate, where skip is counted as half.
+// Size is elapsed seconds summed across runs, tests with zero elapsed time have minElapsed.
+type TestTreemapBuilder struct{}
+
+// NewTestTreemapBuilder is constructor.
+func NewTestTreemapBuilder() TestTreemapBuilder {
+	return TestTreemapBuilder{}
+}
+
+// TestTreemapFromEvents from events.
+// Heat of packages with tests is not set, so that it can be imputed from tests.
+func (s TestTreemapBuilder) TestTreemapFromEvents(ctx context.Context, events []TestEvent) (*treemap.Tree, error) {
+	if len(events) == 0 {
+		return nil, errors.New("no events passed")
+	}
+
+	stats := map[string]testStats{}
+	var paths []string
+	hasTests := map[string]bool{}
+
+	for _, event := range events {
+		if event.Package == "" {
+			continue
+		}
+
+		path := event.Package
+		if event.Test != "" {
+			path = event.Package + "/" + event.Test
+			hasTests[event.Package] = true
+		}
+
+		v, ok := stats[path]
+		if !ok {
+			paths = append(paths, path)
+		}
+		switch event.Action {
+		case "pass":
+			v.pass++
+		case "fail":
+			v.fail++
+		case "skip":
+			v.skip++
+		}
+		// elapsed is set only in final events
+		v.elapsed += event.Elapsed
+		stats[path] = v
+	}
+
+	tree := treemap.Tree{
+		Nodes: map[string]treemap.Node{},
+		To:    map[string][]string{},
+	}
+
+	for _, path := range paths {
+		v := stats[path]
+		if v.runs() == 0 {
+			// test did not finish, e.g. build failed or run was interrupted
+			continue
+		}
+		node := treemap.Node{
+			Path: path,
+			Size: math.Max(v.elapsed, minElapsed),
+		}
+		if !hasTests[path] {
+			node.Heat = v.heat()
+			node.HasHeat = true
+		}
+		tree.Nodes[path] = node
+	}
+
+	if len(tree.Nodes) == 0 {
+		return nil, errors.New("no finished tests or packages")
+	}
+
+	// for finding roots
+	hasParent := map[string]bool{}
+
+	for _, path := range paths {
+		if _, ok := tree.Nodes[path]; !ok {
+			continue
+		}
+
+		treemap.AddPath(&tree, hasParent, path)
+	}
+
+	if err := treemap.SetRoot(&tree, hasParent); err != nil {
+		return nil, err
+	}
+
+	return &tree, nil
+}
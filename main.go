@@ -1,15 +1,19 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"image/color"
 	"io"
+	"io/fs"
 	"log"
 	"math/rand"
+	"mime/multipart"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	chirender "github.com/go-chi/render"
@@ -22,21 +26,515 @@ import (
 	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
 	"golang.org/x/tools/cover"
 
+	"github.com/nikolaydubina/go-instrument-example/go-binsize-treemap/binsizetreemap"
 	"github.com/nikolaydubina/go-instrument-example/go-cover-treemap/covertreemap"
+	"github.com/nikolaydubina/go-instrument-example/go-fs-treemap/fstreemap"
+	"github.com/nikolaydubina/go-instrument-example/go-pprof-treemap/pproftreemap"
+	"github.com/nikolaydubina/go-instrument-example/go-test-treemap/testtreemap"
 	"github.com/nikolaydubina/go-instrument-example/treemap"
+	"github.com/nikolaydubina/go-instrument-example/treemap/layout"
 	"github.com/nikolaydubina/go-instrument-example/treemap/render"
 )
 
 var grey = color.RGBA{128, 128, 128, 255}
 
-func makeCover(ctx context.Context, width float64, height float64, in io.Reader, out io.Writer) (err error) {
-	profiles, err := cover.ParseProfilesFromReader(in)
+var formats = render.NewDefaultFormats()
+
+var transforms = func() *treemap.Transforms {
+	transforms := treemap.NewDefaultTransforms()
+	covertreemap.RegisterTransforms(transforms)
+	return transforms
+}()
+
+// defaultCoverPipeline is used when request does not have pipeline.
+const defaultCoverPipeline = "sum-size:empty=1,set-names,collapse-long-paths,weighted-heat:empty=0.5"
+
+// coverRequest is profiles and options of coverage treemap from request.
+type coverRequest struct {
+	profiles []*cover.Profile
+	funcs    bool
+	hitsHeat bool
+	size     covertreemap.SizeMetric
+	sources  covertreemap.SourceFinder
+	modules  []string
+	filter   treemap.PathFilter
+	pipeline treemap.Pipeline
+	rules    []covertreemap.CoverageRule
+	layout   layout.Layout
+	previous *render.UIBox
+	// polygonLayout is used instead of layout if set
+	polygonLayout layout.PolygonLayout
+	container     func(box layout.Box) layout.Polygon
+	view          string
+	wrapText      bool
+	centerText    bool
+}
+
+// coverBuilder makes builder from options of request, size and heat are set by caller.
+func coverBuilder(req coverRequest) covertreemap.CoverageTreemapBuilder {
+	treemapBuilder := covertreemap.NewCoverageTreemapBuilder(true).WithPathFilter(req.filter)
+	if req.funcs {
+		treemapBuilder = treemapBuilder.WithFunctions(req.sources)
+	}
+	if req.modules != nil {
+		treemapBuilder = treemapBuilder.WithModules(req.modules...)
+	}
+	return treemapBuilder
+}
+
+// checkCover evaluates rules on tree with coverage heat and statements size,
+// so that coverage of parents is same regardless of what is rendered.
+func checkCover(ctx context.Context, req coverRequest) ([]covertreemap.CoverageViolation, error) {
+	tree, err := coverBuilder(req).CoverageTreemapFromProfiles(ctx, req.profiles)
+	if err != nil {
+		return nil, fmt.Errorf("can not build tree: %w", err)
+	}
+
+	sizeImputer := treemap.SumSizeImputer{EmptyLeafSize: 1}
+	sizeImputer.ImputeSize(ctx, *tree)
+
+	heatImputer := treemap.WeightedHeatImputer{EmptyLeafHeat: 0.5}
+	heatImputer.ImputeHeat(ctx, *tree)
+
+	evaluator := covertreemap.CoverageEvaluator{Rules: req.rules}
+	return evaluator.Evaluate(ctx, *tree), nil
+}
+
+// makeCoverSpec makes coverage treemap, if there are rules then violating boxes are highlighted.
+// If there is previous treemap, then boxes are kept close to where they were in it.
+// Same tree can be drawn as other view than treemap, then layout options are not used.
+func makeCoverSpec(ctx context.Context, width float64, height float64, req coverRequest) (spec render.UIBox, err error) {
+	var borderer render.Borderer
+	if len(req.rules) > 0 {
+		violations, err := checkCover(ctx, req)
+		if err != nil {
+			return spec, fmt.Errorf("can not check coverage: %w", err)
+		}
+		borderer = covertreemap.ViolationBorderer{
+			Violations: violations,
+			Color:      covertreemap.ViolationBorderColor,
+			Width:      3,
+		}
+	}
+
+	treemapBuilder := coverBuilder(req)
+	if req.size != nil {
+		treemapBuilder = treemapBuilder.WithSizeMetric(req.size)
+	}
+	paletteName := "RdYlGn"
+	if req.hitsHeat {
+		treemapBuilder = treemapBuilder.WithHitsHeat()
+		paletteName = "YlOrRd"
+	}
+	tree, err := treemapBuilder.CoverageTreemapFromProfiles(ctx, req.profiles)
+	if err != nil {
+		return spec, fmt.Errorf("can not build tree: %w", err)
+	}
+
+	if err := req.pipeline.Transform(ctx, tree); err != nil {
+		return spec, fmt.Errorf("can not transform tree: %w", err)
+	}
+
+	palette, ok := render.GetPalette(ctx, paletteName)
+	if !ok {
+		return spec, errors.New("can not get palette")
+	}
+	uiBuilder := render.UITreeMapBuilder{
+		Colorer:       render.HeatColorer{Palette: palette},
+		BorderColor:   grey,
+		Borderer:      borderer,
+		Layout:        req.layout,
+		PolygonLayout: req.polygonLayout,
+		Container:     req.container,
+
+		WrapText:       req.wrapText,
+		CenterLeafText: req.centerText,
+	}
+	if req.previous != nil {
+		uiBuilder.Hints = render.NewLayoutHints(*req.previous)
+	}
+
+	switch req.view {
+	case "circles":
+		return uiBuilder.NewUICircles(ctx, *tree, width, height, 2, 4, 16), nil
+	case "sunburst":
+		return uiBuilder.NewUISunburst(ctx, *tree, width, height, 16), nil
+	case "icicle":
+		return uiBuilder.NewUIIcicle(ctx, *tree, width, height, 1, 16), nil
+	default:
+		return uiBuilder.NewUITreeMap(ctx, *tree, width, height, 4, 4, 16), nil
+	}
+}
+
+// maxImageSize is largest width and height of image, raster image of this size takes 256MB.
+const maxImageSize = 8192
+
+// imageSize is width and height of image from query parameters, 600 if not set.
+func imageSize(r *http.Request) (width, height int, err error) {
+	query := r.URL.Query()
+	if width, err = imageSizeParam(query.Get("w")); err != nil {
+		return 0, 0, fmt.Errorf("bad width: %w", err)
+	}
+	if height, err = imageSizeParam(query.Get("h")); err != nil {
+		return 0, 0, fmt.Errorf("bad height: %w", err)
+	}
+	return width, height, nil
+}
+
+func imageSizeParam(v string) (int, error) {
+	if v == "" {
+		return 600, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, err
+	}
+	if n <= 0 || n > maxImageSize {
+		return 0, fmt.Errorf("got %d, expected 1~%d", n, maxImageSize)
+	}
+	return n, nil
+}
+
+// writeRendered renders into buffer and writes it only on success,
+// so that on error status is 400 and client does not get part of image.
+func writeRendered(w http.ResponseWriter, r *http.Request, contentType string, write func(out io.Writer) error) {
+	var b bytes.Buffer
+	if err := write(&b); err != nil {
+		chirender.Status(r, 400)
+		chirender.JSON(w, r, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Write(b.Bytes())
+}
+
+// outputFormat picks output format from query parameter or from Accept header.
+// Default format is SVG.
+func outputFormat(r *http.Request) (render.Format, error) {
+	if name := r.URL.Query().Get("format"); name != "" {
+		format, ok := formats.ByName(name)
+		if !ok {
+			return render.Format{}, fmt.Errorf("unknown format(%s), expected one of %v", name, formats.Names())
+		}
+		return format, nil
+	}
+	if format, ok := formats.Negotiate(r.Header.Get("Accept")); ok {
+		return format, nil
+	}
+	format, _ := formats.ByName("svg")
+	return format, nil
+}
+
+func makeCoverDiff(ctx context.Context, width float64, height float64, renderer render.Renderer, base io.Reader, curr io.Reader, out io.Writer) (err error) {
+	baseProfiles, err := covertreemap.ParseMergedProfilesFromReader(base)
+	if err != nil {
+		return fmt.Errorf("can not parse baseline file: %w", err)
+	}
+	currProfiles, err := covertreemap.ParseMergedProfilesFromReader(curr)
+	if err != nil {
+		return fmt.Errorf("can not parse current file: %w", err)
+	}
+
+	treemapBuilder := covertreemap.NewCoverageDiffTreemapBuilder(true)
+	tree, statuses, err := treemapBuilder.CoverageDiffTreemapFromProfiles(ctx, baseProfiles, currProfiles)
+	if err != nil {
+		return fmt.Errorf("can not build tree: %w", err)
+	}
+
+	sizeImputer := treemap.SumSizeImputer{EmptyLeafSize: 1}
+	sizeImputer.ImputeSize(ctx, *tree)
+	treemap.SetNamesFromPaths(ctx, tree)
+	treemap.CollapseLongPaths(ctx, tree)
+
+	palette, ok := render.GetPalette(ctx, "RdBu")
+	if !ok {
+		return errors.New("can not get palette")
+	}
+	uiBuilder := render.UITreeMapBuilder{
+		Colorer: covertreemap.DiffColorer{
+			Colorer:      render.HeatColorer{Palette: palette},
+			Statuses:     statuses,
+			AddedColor:   covertreemap.DiffAddedColor,
+			RemovedColor: covertreemap.DiffRemovedColor,
+		},
+		BorderColor: grey,
+	}
+	spec := uiBuilder.NewUITreeMap(ctx, *tree, width, height, 4, 4, 16)
+
+	if err := renderer.Render(ctx, out, spec, width, height); err != nil {
+		return fmt.Errorf("can not render: %w", err)
+	}
+	return nil
+}
+
+// parseCoverRequest reads profiles, optional go.mod, sources and rules files and query parameters.
+func parseCoverRequest(r *http.Request) (req coverRequest, err error) {
+	query := r.URL.Query()
+
+	req.funcs, _ = strconv.ParseBool(query.Get("funcs"))
+	switch query.Get("heat") {
+	case "", "coverage":
+	case "hits":
+		req.hitsHeat = true
+	default:
+		return req, fmt.Errorf("unknown heat(%s), expected coverage or hits", query.Get("heat"))
+	}
+	if polygonLayout, ok := layout.PolygonLayouts[query.Get("layout")]; ok {
+		req.polygonLayout = polygonLayout
+	} else if req.layout, err = layout.ByName(query.Get("layout")); err != nil {
+		return req, fmt.Errorf("%w or polygon layout one of %v", err, layout.PolygonLayoutNames)
+	}
+	req.wrapText, _ = strconv.ParseBool(query.Get("wrap"))
+	req.centerText, _ = strconv.ParseBool(query.Get("center"))
+	switch req.view = query.Get("view"); req.view {
+	case "", "treemap", "circles", "sunburst", "icicle":
+	default:
+		return req, fmt.Errorf("unknown view(%s), expected treemap, circles, sunburst or icicle", req.view)
+	}
+	switch query.Get("container") {
+	case "", "rect":
+	case "circle":
+		req.container = func(box layout.Box) layout.Polygon { return layout.CirclePolygon(box, 128) }
+	default:
+		return req, fmt.Errorf("unknown container(%s), expected rect or circle", query.Get("container"))
+	}
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		return req, err
+	}
+
+	// profiles of sharded runs are merged
+	files := r.MultipartForm.File["profile"]
+	if len(files) == 0 {
+		return req, errors.New("expected at least 1 profile")
+	}
+	for i, fh := range files {
+		data, err := readFormFile(fh)
+		if err != nil {
+			return req, err
+		}
+		profiles, err := covertreemap.ParseMergedProfilesFromReader(bytes.NewReader(data))
+		if err != nil {
+			return req, fmt.Errorf("can not parse profile(%d): %w", i, err)
+		}
+		req.profiles = append(req.profiles, profiles...)
+	}
+	if req.profiles, err = covertreemap.MergeProfiles(req.profiles); err != nil {
+		return req, fmt.Errorf("can not merge profiles: %w", err)
+	}
+
+	// grouping by modules is on if any of modules, module paths or go.mod files is set
+	groupModules, _ := strconv.ParseBool(query.Get("modules"))
+	modules := query["module"]
+	for _, fh := range r.MultipartForm.File["gomod"] {
+		data, err := readFormFile(fh)
+		if err != nil {
+			return req, err
+		}
+		module, err := covertreemap.ModulePathFromGoMod(data)
+		if err != nil {
+			return req, err
+		}
+		modules = append(modules, module)
+	}
+	if groupModules && modules == nil {
+		modules = []string{}
+	}
+	req.modules = modules
+
+	// sources are only from archive, files of server are never read,
+	// module path is trimmed from file names to find them in it,
+	// it is first of module paths or from go.mod in archive
+	if sources := r.MultipartForm.File["sources"]; len(sources) > 0 {
+		data, err := readFormFile(sources[0])
+		if err != nil {
+			return req, err
+		}
+		fsys, err := fstreemap.ReadTarFS(bytes.NewReader(data))
+		if err != nil {
+			return req, err
+		}
+		finder := covertreemap.FSSourceFinder{FS: fsys}
+		if len(modules) > 0 {
+			finder.ModulePath = modules[0]
+		} else if data, err := fs.ReadFile(fsys, "go.mod"); err == nil {
+			finder.ModulePath, _ = covertreemap.ModulePathFromGoMod(data)
+		}
+		req.sources = finder
+	}
+	if req.funcs && req.sources == nil {
+		return req, errors.New("funcs requires sources archive")
+	}
+
+	switch query.Get("size") {
+	case "", "statements":
+		req.size = covertreemap.StatementsSize{}
+	case "uncovered":
+		req.size = covertreemap.UncoveredStatementsSize{}
+	case "blocks":
+		req.size = covertreemap.BlocksSize{}
+	case "lines":
+		if req.sources == nil {
+			return req, errors.New("size lines requires sources archive")
+		}
+		req.size = covertreemap.LinesSize{Sources: req.sources}
+	case "files":
+		req.size = covertreemap.FilesSize{}
+	default:
+		return req, fmt.Errorf("unknown size(%s), expected statements, uncovered, blocks, lines or files", query.Get("size"))
+	}
+
+	// files are filtered before computing coverage
+	if req.filter, err = treemap.NewPathFilter(query["include"], query["exclude"]); err != nil {
+		return req, err
+	}
+
+	// pipeline is either query parameter or form field
+	pipelineSpec := r.FormValue("pipeline")
+	if pipelineSpec == "" {
+		pipelineSpec = defaultCoverPipeline
+	}
+	spec, err := treemap.ParsePipelineSpec(pipelineSpec)
+	if err != nil {
+		return req, err
+	}
+	// pruning is after imputing, so that sizes and heats of cut nodes are set
+	if v := query.Get("depth"); v != "" {
+		spec = append(spec, treemap.TransformSpec{Name: "depth", Args: map[string]string{"max": v}})
+	}
+	if v := query.Get("top"); v != "" {
+		spec = append(spec, treemap.TransformSpec{Name: "top", Args: map[string]string{"n": v}})
+	}
+	if req.pipeline, err = transforms.NewPipeline(spec); err != nil {
+		return req, err
+	}
+
+	// previous treemap is JSON spec of same request made before
+	if previous := r.MultipartForm.File["previous"]; len(previous) > 0 {
+		data, err := readFormFile(previous[0])
+		if err != nil {
+			return req, err
+		}
+		spec, err := render.ParseJSONUITreeMapFromReader(bytes.NewReader(data))
+		if err != nil {
+			return req, fmt.Errorf("can not parse previous: %w", err)
+		}
+		req.previous = &spec
+	}
+	if req.previous != nil && (req.polygonLayout != nil || (req.view != "" && req.view != "treemap")) {
+		return req, errors.New("previous is supported only in treemap view with rectangle layouts")
+	}
+
+	if rules := r.MultipartForm.File["rules"]; len(rules) > 0 {
+		data, err := readFormFile(rules[0])
+		if err != nil {
+			return req, err
+		}
+		if req.rules, err = covertreemap.ParseCoverageRulesFromReader(bytes.NewReader(data)); err != nil {
+			return req, err
+		}
+	}
+
+	return req, nil
+}
+
+func readFormFile(fh *multipart.FileHeader) ([]byte, error) {
+	f, err := fh.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+func coverHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	width, height, err := imageSize(r)
+	if err != nil {
+		chirender.Status(r, 400)
+		chirender.JSON(w, r, err.Error())
+		return
+	}
+	req, err := parseCoverRequest(r)
+	if err != nil {
+		chirender.Status(r, 400)
+		chirender.JSON(w, r, err.Error())
+		return
+	}
+
+	format, err := outputFormat(r)
+	if err != nil {
+		chirender.Status(r, 400)
+		chirender.JSON(w, r, err.Error())
+		return
+	}
+
+	spec, err := makeCoverSpec(ctx, float64(width), float64(height), req)
+	if err != nil {
+		chirender.Status(r, 400)
+		chirender.JSON(w, r, err.Error())
+		return
+	}
+	if req.previous != nil {
+		w.Header().Set("X-Layout-Drift", strconv.FormatFloat(render.LayoutDrift(ctx, *req.previous, spec), 'f', 4, 64))
+	}
+
+	writeRendered(w, r, format.MIMEType, func(out io.Writer) error {
+		return format.Renderer.Render(ctx, out, spec, float64(width), float64(height))
+	})
+}
+
+// coverCheckResult is response of coverage check.
+type coverCheckResult struct {
+	Passed     bool                             `json:"passed"`
+	Violations []covertreemap.CoverageViolation `json:"violations"`
+}
+
+// coverCheckHandler expects profiles and rules file, same options as for coverage treemap apply.
+// Status is 422 if any of rules is violated.
+func coverCheckHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	req, err := parseCoverRequest(r)
+	if err != nil {
+		chirender.Status(r, 400)
+		chirender.JSON(w, r, err.Error())
+		return
+	}
+	if len(req.rules) == 0 {
+		chirender.Status(r, 400)
+		chirender.JSON(w, r, "expected rules")
+		return
+	}
+
+	violations, err := checkCover(ctx, req)
+	if err != nil {
+		chirender.Status(r, 400)
+		chirender.JSON(w, r, err.Error())
+		return
+	}
+
+	result := coverCheckResult{
+		Passed:     len(violations) == 0,
+		Violations: violations,
+	}
+	if result.Violations == nil {
+		result.Violations = []covertreemap.CoverageViolation{}
+	}
+	if !result.Passed {
+		chirender.Status(r, http.StatusUnprocessableEntity)
+	}
+	chirender.JSON(w, r, result)
+}
+
+func makeTests(ctx context.Context, width float64, height float64, renderer render.Renderer, in io.Reader, out io.Writer) (err error) {
+	events, err := testtreemap.ParseTestEventsFromReader(in)
 	if err != nil {
 		return fmt.Errorf("can not parse file: %w", err)
 	}
 
-	treemapBuilder := covertreemap.NewCoverageTreemapBuilder(true)
-	tree, err := treemapBuilder.CoverageTreemapFromProfiles(ctx, profiles)
+	treemapBuilder := testtreemap.NewTestTreemapBuilder()
+	tree, err := treemapBuilder.TestTreemapFromEvents(ctx, events)
 	if err != nil {
 		return fmt.Errorf("can not build tree: %w", err)
 	}
@@ -58,24 +556,223 @@ func makeCover(ctx context.Context, width float64, height float64, in io.Reader,
 		BorderColor: grey,
 	}
 	spec := uiBuilder.NewUITreeMap(ctx, *tree, width, height, 4, 4, 16)
-	renderer := render.SVGRenderer{}
 
-	out.Write(renderer.Render(ctx, spec, width, height))
+	if err := renderer.Render(ctx, out, spec, width, height); err != nil {
+		return fmt.Errorf("can not render: %w", err)
+	}
 	return nil
 }
 
-func coverHandler(w http.ResponseWriter, r *http.Request) {
+func makePprof(ctx context.Context, width float64, height float64, sampleType string, withHeat bool, renderer render.Renderer, in io.Reader, out io.Writer) (err error) {
+	profile, err := pproftreemap.ParseProfileFromReader(in)
+	if err != nil {
+		return fmt.Errorf("can not parse file: %w", err)
+	}
+
+	treemapBuilder := pproftreemap.NewPprofTreemapBuilder(sampleType, withHeat)
+	tree, err := treemapBuilder.PprofTreemapFromProfile(ctx, profile)
+	if err != nil {
+		return fmt.Errorf("can not build tree: %w", err)
+	}
+
+	sizeImputer := treemap.SumSizeImputer{EmptyLeafSize: 1}
+	sizeImputer.ImputeSize(ctx, *tree)
+	treemap.SetNamesFromPaths(ctx, tree)
+	treemap.CollapseLongPaths(ctx, tree)
+
+	heatImputer := treemap.WeightedHeatImputer{EmptyLeafHeat: 0.5}
+	heatImputer.ImputeHeat(ctx, *tree)
+
+	palette, ok := render.GetPalette(ctx, "RdBu")
+	if !ok {
+		return errors.New("can not get palette")
+	}
+	uiBuilder := render.UITreeMapBuilder{
+		Colorer:     render.HeatColorer{Palette: palette},
+		BorderColor: grey,
+	}
+	spec := uiBuilder.NewUITreeMap(ctx, *tree, width, height, 4, 4, 16)
+
+	if err := renderer.Render(ctx, out, spec, width, height); err != nil {
+		return fmt.Errorf("can not render: %w", err)
+	}
+	return nil
+}
+
+// makeBinsize makes treemap of current binary, base is optional and gives heat of growth.
+func makeBinsize(ctx context.Context, width float64, height float64, renderer render.Renderer, base io.ReaderAt, curr io.ReaderAt, out io.Writer) (err error) {
+	currBinary, err := binsizetreemap.ReadELFBinary(curr)
+	if err != nil {
+		return fmt.Errorf("can not read current binary: %w", err)
+	}
+	var baseBinary *binsizetreemap.Binary
+	if base != nil {
+		if baseBinary, err = binsizetreemap.ReadELFBinary(base); err != nil {
+			return fmt.Errorf("can not read baseline binary: %w", err)
+		}
+	}
+
+	treemapBuilder := binsizetreemap.NewBinarySizeTreemapBuilder()
+	tree, err := treemapBuilder.BinarySizeDiffTreemapFromBinaries(ctx, baseBinary, currBinary)
+	if err != nil {
+		return fmt.Errorf("can not build tree: %w", err)
+	}
+
+	sizeImputer := treemap.SumSizeImputer{EmptyLeafSize: 1}
+	sizeImputer.ImputeSize(ctx, *tree)
+	treemap.SetNamesFromPaths(ctx, tree)
+	treemap.CollapseLongPaths(ctx, tree)
+
+	heatImputer := treemap.WeightedHeatImputer{EmptyLeafHeat: 0.5}
+	heatImputer.ImputeHeat(ctx, *tree)
+
+	palette, ok := render.GetPalette(ctx, "RdBu")
+	if !ok {
+		return errors.New("can not get palette")
+	}
+	uiBuilder := render.UITreeMapBuilder{
+		Colorer:     render.HeatColorer{Palette: palette},
+		BorderColor: grey,
+	}
+	spec := uiBuilder.NewUITreeMap(ctx, *tree, width, height, 4, 4, 16)
+
+	if err := renderer.Render(ctx, out, spec, width, height); err != nil {
+		return fmt.Errorf("can not render: %w", err)
+	}
+	return nil
+}
+
+// makeFS makes treemap of files in tar archive, heat is from metric if it is set.
+func makeFS(ctx context.Context, width float64, height float64, countLines bool, heat fstreemap.HeatMetric, exclude []string, renderer render.Renderer, in io.Reader, out io.Writer) (err error) {
+	fsys, err := fstreemap.ReadTarFS(in)
+	if err != nil {
+		return fmt.Errorf("can not read archive: %w", err)
+	}
+
+	treemapBuilder := fstreemap.NewFSTreemapBuilder(countLines).WithIgnore(fstreemap.NewIgnore(exclude...))
+	if heat != nil {
+		treemapBuilder = treemapBuilder.WithHeat(heat)
+	}
+	tree, err := treemapBuilder.FSTreemap(ctx, fsys, ".")
+	if err != nil {
+		return fmt.Errorf("can not build tree: %w", err)
+	}
+
+	sizeImputer := treemap.SumSizeImputer{EmptyLeafSize: 1}
+	sizeImputer.ImputeSize(ctx, *tree)
+	treemap.SetNamesFromPaths(ctx, tree)
+	treemap.CollapseLongPaths(ctx, tree)
+
+	heatImputer := treemap.WeightedHeatImputer{EmptyLeafHeat: 0.5}
+	heatImputer.ImputeHeat(ctx, *tree)
+
+	palette, ok := render.GetPalette(ctx, "RdYlGn")
+	if !ok {
+		return errors.New("can not get palette")
+	}
+	uiBuilder := render.UITreeMapBuilder{
+		Colorer:     render.HeatColorer{Palette: palette},
+		BorderColor: grey,
+	}
+	spec := uiBuilder.NewUITreeMap(ctx, *tree, width, height, 4, 4, 16)
+
+	if err := renderer.Render(ctx, out, spec, width, height); err != nil {
+		return fmt.Errorf("can not render: %w", err)
+	}
+	return nil
+}
+
+// coverDiffHandler expects two profile files, first is baseline and second is current.
+func coverDiffHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	var width, height int
+	width, height, err := imageSize(r)
+	if err != nil {
+		chirender.Status(r, 400)
+		chirender.JSON(w, r, err.Error())
+		return
+	}
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		chirender.Status(r, 400)
+		chirender.JSON(w, r, err.Error())
+		return
+	}
+	files := r.MultipartForm.File["profile"]
+	if len(files) != 2 {
+		chirender.Status(r, 400)
+		chirender.JSON(w, r, fmt.Sprintf("expected 2 profiles, got %d", len(files)))
+		return
+	}
+	base, err := files[0].Open()
+	if err != nil {
+		chirender.Status(r, 400)
+		chirender.JSON(w, r, err.Error())
+		return
+	}
+	defer base.Close()
+	curr, err := files[1].Open()
+	if err != nil {
+		chirender.Status(r, 400)
+		chirender.JSON(w, r, err.Error())
+		return
+	}
+	defer curr.Close()
 
-	query := r.URL.Query()
-	if width, _ = strconv.Atoi(query.Get("w")); width == 0 {
-		width = 600
+	format, err := outputFormat(r)
+	if err != nil {
+		chirender.Status(r, 400)
+		chirender.JSON(w, r, err.Error())
+		return
+	}
+	writeRendered(w, r, format.MIMEType, func(out io.Writer) error {
+		return makeCoverDiff(ctx, float64(width), float64(height), format.Renderer, base, curr, out)
+	})
+}
+
+// testsHandler expects `go test -json` output.
+func testsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	width, height, err := imageSize(r)
+	if err != nil {
+		chirender.Status(r, 400)
+		chirender.JSON(w, r, err.Error())
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		chirender.Status(r, 400)
+		chirender.JSON(w, r, err.Error())
+		return
+	}
+	events, _, err := r.FormFile("events")
+	if err != nil {
+		chirender.Status(r, 400)
+		chirender.JSON(w, r, err.Error())
+		return
+	}
+
+	format, err := outputFormat(r)
+	if err != nil {
+		chirender.Status(r, 400)
+		chirender.JSON(w, r, err.Error())
+		return
 	}
-	if height, _ = strconv.Atoi(query.Get("h")); height == 0 {
-		height = 600
+	writeRendered(w, r, format.MIMEType, func(out io.Writer) error {
+		return makeTests(ctx, float64(width), float64(height), format.Renderer, events, out)
+	})
+}
+
+func pprofHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	width, height, err := imageSize(r)
+	if err != nil {
+		chirender.Status(r, 400)
+		chirender.JSON(w, r, err.Error())
+		return
 	}
+	query := r.URL.Query()
+	withHeat, _ := strconv.ParseBool(query.Get("heat"))
 	if err := r.ParseForm(); err != nil {
 		chirender.Status(r, 400)
 		chirender.JSON(w, r, err.Error())
@@ -88,11 +785,140 @@ func coverHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := makeCover(ctx, float64(width), float64(height), profile, w); err != nil {
+	format, err := outputFormat(r)
+	if err != nil {
+		chirender.Status(r, 400)
+		chirender.JSON(w, r, err.Error())
+		return
+	}
+	writeRendered(w, r, format.MIMEType, func(out io.Writer) error {
+		return makePprof(ctx, float64(width), float64(height), query.Get("sample"), withHeat, format.Renderer, profile, out)
+	})
+}
+
+// binsizeHandler expects one or two ELF binaries, if two then first is baseline and second is current.
+func binsizeHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	width, height, err := imageSize(r)
+	if err != nil {
+		chirender.Status(r, 400)
+		chirender.JSON(w, r, err.Error())
+		return
+	}
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		chirender.Status(r, 400)
+		chirender.JSON(w, r, err.Error())
+		return
+	}
+	files := r.MultipartForm.File["binary"]
+	if len(files) != 1 && len(files) != 2 {
+		chirender.Status(r, 400)
+		chirender.JSON(w, r, fmt.Sprintf("expected 1 or 2 binaries, got %d", len(files)))
+		return
+	}
+
+	var base io.ReaderAt
+	if len(files) == 2 {
+		f, err := files[0].Open()
+		if err != nil {
+			chirender.Status(r, 400)
+			chirender.JSON(w, r, err.Error())
+			return
+		}
+		defer f.Close()
+		base = f
+	}
+	curr, err := files[len(files)-1].Open()
+	if err != nil {
+		chirender.Status(r, 400)
+		chirender.JSON(w, r, err.Error())
+		return
+	}
+	defer curr.Close()
+
+	format, err := outputFormat(r)
+	if err != nil {
+		chirender.Status(r, 400)
+		chirender.JSON(w, r, err.Error())
+		return
+	}
+	writeRendered(w, r, format.MIMEType, func(out io.Writer) error {
+		return makeBinsize(ctx, float64(width), float64(height), format.Renderer, base, curr, out)
+	})
+}
+
+// fsHandler expects tar archive, possibly gzipped.
+// Heat is age of files, or churn from file of `uniq -c` lines.
+func fsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	width, height, err := imageSize(r)
+	if err != nil {
+		chirender.Status(r, 400)
+		chirender.JSON(w, r, err.Error())
+		return
+	}
+	query := r.URL.Query()
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		chirender.Status(r, 400)
+		chirender.JSON(w, r, err.Error())
+		return
+	}
+	archive, _, err := r.FormFile("archive")
+	if err != nil {
+		chirender.Status(r, 400)
+		chirender.JSON(w, r, err.Error())
+		return
+	}
+	defer archive.Close()
+
+	var countLines bool
+	switch query.Get("size") {
+	case "", "bytes":
+	case "lines":
+		countLines = true
+	default:
+		chirender.Status(r, 400)
+		chirender.JSON(w, r, fmt.Sprintf("unknown size(%s), expected bytes or lines", query.Get("size")))
+		return
+	}
+
+	var heat fstreemap.HeatMetric
+	switch query.Get("heat") {
+	case "":
+	case "age":
+		heat = fstreemap.AgeHeat{Now: time.Now()}
+	case "churn":
+		churn, _, err := r.FormFile("churn")
+		if err != nil {
+			chirender.Status(r, 400)
+			chirender.JSON(w, r, err.Error())
+			return
+		}
+		defer churn.Close()
+		changes, err := fstreemap.ParseChurnFromReader(churn)
+		if err != nil {
+			chirender.Status(r, 400)
+			chirender.JSON(w, r, err.Error())
+			return
+		}
+		heat = fstreemap.ChurnHeat{Changes: changes}
+	default:
+		chirender.Status(r, 400)
+		chirender.JSON(w, r, fmt.Sprintf("unknown heat(%s), expected age or churn", query.Get("heat")))
+		return
+	}
+
+	format, err := outputFormat(r)
+	if err != nil {
 		chirender.Status(r, 400)
 		chirender.JSON(w, r, err.Error())
 		return
 	}
+	writeRendered(w, r, format.MIMEType, func(out io.Writer) error {
+		return makeFS(ctx, float64(width), float64(height), countLines, heat, query["exclude"], format.Renderer, archive, out)
+	})
 }
 
 func fib(ctx context.Context, n int, makeErr bool) (v int, err error) {
@@ -160,6 +986,12 @@ func main() {
 	)
 
 	router.Post("/cover", coverHandler)
+	router.Post("/cover/diff", coverDiffHandler)
+	router.Post("/cover/check", coverCheckHandler)
+	router.Post("/tests", testsHandler)
+	router.Post("/pprof", pprofHandler)
+	router.Post("/binsize", binsizeHandler)
+	router.Post("/fs", fsHandler)
 	router.Get("/fib/{n}", fibHandler)
 
 	log.Fatal(http.ListenAndServe(":8080", router))
@@ -47,11 +47,12 @@ func CollapseLongPathsFromNode(ctx context.Context, t *Tree, nodeName string) {
 
 		// copy fields from child to current node
 		t.Nodes[nodeName] = Node{
-			Path:    node.Path,
-			Name:    strings.Join(parts, "/"),
-			Size:    node.Size,
-			Heat:    node.Heat,
-			HasHeat: node.HasHeat,
+			Path:       node.Path,
+			Name:       strings.Join(parts, "/"),
+			Size:       node.Size,
+			Heat:       node.Heat,
+			HasHeat:    node.HasHeat,
+			Attributes: node.Attributes,
 		}
 
 		// delete last child, since it is unreachable now
@@ -0,0 +1,209 @@
+package layout
+
+import (
+	"context"
+	"math"
+	"sort"
+)
+
+type Circle struct {
+	X float64
+	Y float64
+	R float64
+}
+
+// Polygon is circle approximated by regular polygon with n points.
+func (c Circle) Polygon(n int) Polygon {
+	return CirclePolygon(Box{X: c.X - c.R, Y: c.Y - c.R, W: 2 * c.R, H: 2 * c.R}, n)
+}
+
+// PackCircles places circles with given areas inside of container without overlaps.
+// Largest circles are placed first, each next one is tangent to two circles on front chain closest to center,
+// as described in "Visualization of large hierarchical data by circle packing", Weixin Wang et al., 2006
+// Packed circles are scaled to fit container, so areas are relative.
+// Returns circles in same order as areas.
+// Zero areas will have zero-value circle.
+func PackCircles(ctx context.Context, container Circle, areas []float64) []Circle {
+	res := make([]Circle, len(areas))
+	if container.R <= 0 {
+		return res
+	}
+
+	var order []int
+	for i, s := range areas {
+		if s > 0 {
+			order = append(order, i)
+		}
+	}
+	if len(order) == 0 {
+		return res
+	}
+	sort.SliceStable(order, func(i, j int) bool { return areas[order[i]] > areas[order[j]] })
+
+	circles := make([]Circle, len(order))
+	for i, idx := range order {
+		circles[i].R = math.Sqrt(areas[idx] / math.Pi)
+	}
+	packFrontChain(circles)
+
+	enclosing := enclosingCircle(circles)
+	scale := container.R / enclosing.R
+	for i, idx := range order {
+		c := circles[i]
+		res[idx] = Circle{
+			X: container.X + (c.X-enclosing.X)*scale,
+			Y: container.Y + (c.Y-enclosing.Y)*scale,
+			R: c.R * scale,
+		}
+	}
+	return res
+}
+
+// packFrontChain sets centers of circles so that they are tangent to each other around origin.
+func packFrontChain(circles []Circle) {
+	n := len(circles)
+	if n == 0 {
+		return
+	}
+	circles[0].X, circles[0].Y = 0, 0
+	if n == 1 {
+		return
+	}
+	circles[0].X = -circles[1].R
+	circles[1].X, circles[1].Y = circles[0].R, 0
+	if n == 2 {
+		return
+	}
+	placeTangent(&circles[2], circles[1], circles[0])
+
+	// front chain is circular list of circles on boundary of packed ones
+	next, prev := make([]int, n), make([]int, n)
+	a, b, c := 0, 1, 2
+	next[a], prev[c] = b, b
+	next[b], prev[a] = c, c
+	next[c], prev[b] = a, a
+
+	for i := 3; i < n; i++ {
+		placeTangent(&circles[i], circles[a], circles[b])
+
+		// if new circle intersects circle on front chain, then chain is cut there and circle is placed again
+		j, k := next[b], prev[a]
+		sj, sk := circles[b].R, circles[a].R
+		intersected := false
+		for {
+			if sj <= sk {
+				if circlesIntersect(circles[j], circles[i]) {
+					b = j
+					next[a], prev[b] = b, a
+					intersected = true
+					break
+				}
+				sj += circles[j].R
+				j = next[j]
+			} else {
+				if circlesIntersect(circles[k], circles[i]) {
+					a = k
+					next[a], prev[b] = b, a
+					intersected = true
+					break
+				}
+				sk += circles[k].R
+				k = prev[k]
+			}
+			if j == next[k] {
+				break
+			}
+		}
+		if intersected {
+			i--
+			continue
+		}
+
+		// insert new circle between a and b, next pair is one closest to origin
+		prev[i], next[i] = a, b
+		next[a], prev[b] = i, i
+		b = i
+
+		best, bestScore := a, chainScore(circles, a, next[a])
+		for c := next[i]; c != b; c = next[c] {
+			if s := chainScore(circles, c, next[c]); s < bestScore {
+				best, bestScore = c, s
+			}
+		}
+		a, b = best, next[best]
+	}
+}
+
+// placeTangent moves c so that it is tangent to a and b.
+func placeTangent(c *Circle, b, a Circle) {
+	dx, dy := b.X-a.X, b.Y-a.Y
+	d2 := dx*dx + dy*dy
+	if d2 == 0 {
+		c.X, c.Y = a.X+c.R, a.Y
+		return
+	}
+	a2, b2 := (a.R+c.R)*(a.R+c.R), (b.R+c.R)*(b.R+c.R)
+	if a2 > b2 {
+		x := (d2 + b2 - a2) / (2 * d2)
+		y := math.Sqrt(math.Max(0, b2/d2-x*x))
+		c.X, c.Y = b.X-x*dx-y*dy, b.Y-x*dy+y*dx
+	} else {
+		x := (d2 + a2 - b2) / (2 * d2)
+		y := math.Sqrt(math.Max(0, a2/d2-x*x))
+		c.X, c.Y = a.X+x*dx-y*dy, a.Y+x*dy+y*dx
+	}
+}
+
+func circlesIntersect(a, b Circle) bool {
+	dr := a.R + b.R - 1e-6*math.Max(a.R, b.R)
+	dx, dy := b.X-a.X, b.Y-a.Y
+	return dr > 0 && dr*dr > dx*dx+dy*dy
+}
+
+// chainScore is distance to origin of point between two circles on front chain weighted by their radius.
+func chainScore(circles []Circle, i, j int) float64 {
+	a, b := circles[i], circles[j]
+	ab := a.R + b.R
+	dx, dy := (a.X*b.R+b.X*a.R)/ab, (a.Y*b.R+b.Y*a.R)/ab
+	return dx*dx + dy*dy
+}
+
+// enclosingCircle is approximately smallest circle that contains all circles.
+// Center is moved towards farthest circle in smaller and smaller steps, as described in
+// "Smaller core-sets for balls", Mihai Badoiu and Kenneth L. Clarkson, 2003
+func enclosingCircle(circles []Circle) Circle {
+	var c Circle
+	for _, v := range circles {
+		c.X += v.X / float64(len(circles))
+		c.Y += v.Y / float64(len(circles))
+	}
+
+	farthest := func() (Circle, float64) {
+		var f Circle
+		var r float64
+		for _, v := range circles {
+			if d := math.Hypot(v.X-c.X, v.Y-c.Y) + v.R; d >= r {
+				f, r = v, d
+			}
+		}
+		return f, r
+	}
+
+	best, bestR := c, math.Inf(1)
+	for k := 1; k <= 200; k++ {
+		f, r := farthest()
+		if r < bestR {
+			best, bestR = c, r
+		}
+		// far point of farthest circle
+		d := math.Hypot(f.X-c.X, f.Y-c.Y)
+		px, py := f.X, f.Y
+		if d > 0 {
+			px, py = f.X+(f.X-c.X)/d*f.R, f.Y+(f.Y-c.Y)/d*f.R
+		}
+		c.X += (px - c.X) / float64(k+1)
+		c.Y += (py - c.Y) / float64(k+1)
+	}
+	best.R = bestR
+	return best
+}
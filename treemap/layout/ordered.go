@@ -0,0 +1,206 @@
+package layout
+
+import (
+	"context"
+	"fmt"
+	"math"
+)
+
+// Layout partitions box into parts with given areas.
+// Returns boxes in same order as areas, zero areas have zero-value box.
+type Layout func(ctx context.Context, box Box, areas []float64) []Box
+
+// Layouts are all layouts of this package by name.
+var Layouts = map[string]Layout{
+	"squarify":       Squarify,
+	"slice-and-dice": SliceAndDice,
+	"strip":          Strip,
+	"binary":         Binary,
+}
+
+// LayoutNames is names of Layouts in order of preference.
+var LayoutNames = []string{"squarify", "slice-and-dice", "strip", "binary"}
+
+// ByName returns layout by name, empty name is Squarify.
+func ByName(name string) (Layout, error) {
+	if name == "" {
+		return Squarify, nil
+	}
+	if l, ok := Layouts[name]; ok {
+		return l, nil
+	}
+	return nil, fmt.Errorf("unknown layout(%s), expected one of %v", name, LayoutNames)
+}
+
+// SliceAndDice partitions box into parallel slices in order of areas.
+// Slices are cut across longer side of box, so that nested boxes alternate direction.
+// As described in "Tree visualization with tree-maps: 2-d space-filling approach", Ben Shneiderman, 1992
+// Returns boxes in same order as areas.
+// Zero areas will have zero-value box.
+func SliceAndDice(ctx context.Context, box Box, areas []float64) []Box {
+	indices, cleanAreas := nonZeroAreas(ctx, box, areas)
+
+	boxes := sliceBoxes(box, cleanAreas, box.W >= box.H)
+	cutoffOverflows(ctx, box, boxes)
+
+	return restoreOrder(len(areas), indices, boxes)
+}
+
+// Strip partitions box into strips along longer side of box, filled by areas in order.
+// Area is added to current strip while average aspect ratio of strip does not get worse.
+// As described in "Ordered and Quantum Treemaps", Benjamin B. Bederson, Ben Shneiderman, and Martin Wattenberg, 2002
+// Returns boxes in same order as areas.
+// Zero areas will have zero-value box.
+func Strip(ctx context.Context, box Box, areas []float64) []Box {
+	indices, cleanAreas := nonZeroAreas(ctx, box, areas)
+
+	// strips go along longer side, stacked along shorter one
+	horizontal := box.W >= box.H
+	length := box.W
+	if !horizontal {
+		length = box.H
+	}
+
+	var boxes []Box
+	freeSpace := box
+	for start := 0; start < len(cleanAreas); {
+		end := start + 1
+		for end < len(cleanAreas) && averageAspectRatio(cleanAreas[start:end+1], length) <= averageAspectRatio(cleanAreas[start:end], length) {
+			end++
+		}
+
+		var stripArea float64
+		for _, s := range cleanAreas[start:end] {
+			stripArea += s
+		}
+		thickness := stripArea / length
+
+		strip := freeSpace
+		if horizontal {
+			strip.H = thickness
+			freeSpace.Y += thickness
+			freeSpace.H -= thickness
+		} else {
+			strip.W = thickness
+			freeSpace.X += thickness
+			freeSpace.W -= thickness
+		}
+		boxes = append(boxes, sliceBoxes(strip, cleanAreas[start:end], horizontal)...)
+
+		start = end
+	}
+	cutoffOverflows(ctx, box, boxes)
+
+	return restoreOrder(len(areas), indices, boxes)
+}
+
+// Binary partitions box by splitting areas in two groups of about equal total area, keeping order.
+// Box is cut across longer side and each part is partitioned same way.
+// Returns boxes in same order as areas.
+// Zero areas will have zero-value box.
+func Binary(ctx context.Context, box Box, areas []float64) []Box {
+	indices, cleanAreas := nonZeroAreas(ctx, box, areas)
+
+	boxes := make([]Box, 0, len(cleanAreas))
+	boxes = binarySplit(box, cleanAreas, boxes)
+	cutoffOverflows(ctx, box, boxes)
+
+	return restoreOrder(len(areas), indices, boxes)
+}
+
+func binarySplit(box Box, areas []float64, boxes []Box) []Box {
+	if len(areas) == 0 {
+		return boxes
+	}
+	if len(areas) == 1 {
+		return append(boxes, box)
+	}
+
+	var total float64
+	for _, s := range areas {
+		total += s
+	}
+
+	// split at point where left part is closest to half
+	k, left := 1, areas[0]
+	for i := 1; i < len(areas)-1; i++ {
+		if math.Abs(left+areas[i]-total/2) > math.Abs(left-total/2) {
+			break
+		}
+		left += areas[i]
+		k = i + 1
+	}
+
+	a, b := splitBox(box, left, total-left, box.W >= box.H)
+	boxes = binarySplit(a, areas[:k], boxes)
+	return binarySplit(b, areas[k:], boxes)
+}
+
+// sliceBoxes cuts box into slices proportional to areas, left to right if horizontal, top to bottom otherwise.
+func sliceBoxes(box Box, areas []float64, horizontal bool) []Box {
+	var total float64
+	for _, s := range areas {
+		total += s
+	}
+	if total == 0 {
+		return nil
+	}
+
+	boxes := make([]Box, 0, len(areas))
+	offset := 0.0
+	for _, s := range areas {
+		b := box
+		if horizontal {
+			b.X = box.X + offset
+			b.W = box.W * s / total
+			offset += b.W
+		} else {
+			b.Y = box.Y + offset
+			b.H = box.H * s / total
+			offset += b.H
+		}
+		boxes = append(boxes, b)
+	}
+	return boxes
+}
+
+// averageAspectRatio of boxes in strip of given length.
+func averageAspectRatio(areas []float64, length float64) float64 {
+	var total float64
+	for _, s := range areas {
+		total += s
+	}
+	thickness := total / length
+
+	var sum float64
+	for _, s := range areas {
+		w := s / thickness
+		sum += math.Max(w/thickness, thickness/w)
+	}
+	return sum / float64(len(areas))
+}
+
+// nonZeroAreas normalizes areas to box and returns non zero ones with their positions.
+func nonZeroAreas(ctx context.Context, box Box, areas []float64) (indices []int, cleanAreas []float64) {
+	if box.W <= 0 || box.H <= 0 {
+		return nil, nil
+	}
+	for i, s := range normalizeAreas(ctx, areas, (box.W * box.H)) {
+		if s > 0 {
+			indices = append(indices, i)
+			cleanAreas = append(cleanAreas, s)
+		}
+	}
+	return indices, cleanAreas
+}
+
+// restoreOrder places boxes of non zero areas to their positions.
+func restoreOrder(n int, indices []int, boxes []Box) []Box {
+	res := make([]Box, n)
+	for i, idx := range indices {
+		if i < len(boxes) {
+			res[idx] = boxes[i]
+		}
+	}
+	return res
+}
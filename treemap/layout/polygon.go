@@ -0,0 +1,207 @@
+package layout
+
+import (
+	"context"
+	"math"
+)
+
+type Point struct {
+	X float64
+	Y float64
+}
+
+// Polygon is polygon with points in clockwise order, as y axis points down.
+// Layouts make convex polygons, Inset, ClipHalfPlane and Chord expect convex polygon.
+type Polygon []Point
+
+// PolygonLayout partitions convex polygon into convex parts with given areas.
+// Returns polygons in same order as areas, zero areas have nil polygon.
+type PolygonLayout func(ctx context.Context, container Polygon, areas []float64) []Polygon
+
+// PolygonLayouts are all polygon layouts of this package by name.
+var PolygonLayouts = map[string]PolygonLayout{
+	"voronoi": Voronoi,
+}
+
+// PolygonLayoutNames is names of PolygonLayouts in order of preference.
+var PolygonLayoutNames = []string{"voronoi"}
+
+// RectanglePolygon is polygon of box.
+func RectanglePolygon(b Box) Polygon {
+	return Polygon{
+		{X: b.X, Y: b.Y},
+		{X: b.X + b.W, Y: b.Y},
+		{X: b.X + b.W, Y: b.Y + b.H},
+		{X: b.X, Y: b.Y + b.H},
+	}
+}
+
+// CirclePolygon is largest circle in box, approximated by regular polygon with n points.
+func CirclePolygon(b Box, n int) Polygon {
+	if n < 3 {
+		n = 3
+	}
+	r := math.Min(b.W, b.H) / 2
+	cx, cy := b.X+b.W/2, b.Y+b.H/2
+	p := make(Polygon, n)
+	for i := range p {
+		a := 2 * math.Pi * float64(i) / float64(n)
+		p[i] = Point{X: cx + r*math.Cos(a), Y: cy + r*math.Sin(a)}
+	}
+	return p
+}
+
+// AnnularSector is part of ring around center between radiuses r0 and r1 and angles a0 and a1 in radians.
+// Arcs are approximated by points at most 2 degrees apart.
+func AnnularSector(cx, cy, r0, r1, a0, a1 float64) Polygon {
+	n := int(math.Ceil((a1-a0)/(math.Pi/90))) + 1
+	if n < 2 {
+		n = 2
+	}
+	arc := func(r float64, i int) Point {
+		a := a0 + (a1-a0)*float64(i)/float64(n-1)
+		return Point{X: cx + r*math.Cos(a), Y: cy + r*math.Sin(a)}
+	}
+
+	p := make(Polygon, 0, 2*n)
+	for i := 0; i < n; i++ {
+		p = append(p, arc(r1, i))
+	}
+	if r0 <= 0 {
+		return append(p, Point{X: cx, Y: cy})
+	}
+	for i := n - 1; i >= 0; i-- {
+		p = append(p, arc(r0, i))
+	}
+	return p
+}
+
+// Contains is true if point is inside of polygon by even-odd rule.
+func (p Polygon) Contains(v Point) bool {
+	in := false
+	for i, a := range p {
+		b := p[(i+1)%len(p)]
+		if (a.Y > v.Y) != (b.Y > v.Y) && v.X < a.X+(v.Y-a.Y)/(b.Y-a.Y)*(b.X-a.X) {
+			in = !in
+		}
+	}
+	return in
+}
+
+// Area of polygon, zero for polygons with less than 3 points.
+func (p Polygon) Area() float64 {
+	return math.Abs(p.signedArea())
+}
+
+func (p Polygon) signedArea() float64 {
+	if len(p) < 3 {
+		return 0
+	}
+	var s float64
+	for i, a := range p {
+		b := p[(i+1)%len(p)]
+		s += a.X*b.Y - b.X*a.Y
+	}
+	return s / 2
+}
+
+// Centroid is center of mass of polygon.
+func (p Polygon) Centroid() Point {
+	a := p.signedArea()
+	if a == 0 {
+		var c Point
+		for _, v := range p {
+			c.X += v.X / float64(len(p))
+			c.Y += v.Y / float64(len(p))
+		}
+		return c
+	}
+	var cx, cy float64
+	for i, v := range p {
+		w := p[(i+1)%len(p)]
+		f := v.X*w.Y - w.X*v.Y
+		cx += (v.X + w.X) * f
+		cy += (v.Y + w.Y) * f
+	}
+	return Point{X: cx / (6 * a), Y: cy / (6 * a)}
+}
+
+// Bounds is smallest box that contains polygon.
+func (p Polygon) Bounds() Box {
+	if len(p) == 0 {
+		return NilBox
+	}
+	x0, y0, x1, y1 := p[0].X, p[0].Y, p[0].X, p[0].Y
+	for _, v := range p[1:] {
+		x0, x1 = math.Min(x0, v.X), math.Max(x1, v.X)
+		y0, y1 = math.Min(y0, v.Y), math.Max(y1, v.Y)
+	}
+	return Box{X: x0, Y: y0, W: x1 - x0, H: y1 - y0}
+}
+
+// Inset shrinks convex polygon by moving each edge inwards by d.
+// Returns nil if nothing is left.
+func (p Polygon) Inset(d float64) Polygon {
+	if len(p) < 3 || d <= 0 {
+		return p
+	}
+	sign := 1.0
+	if p.signedArea() < 0 {
+		sign = -1
+	}
+	q := p
+	for i, a := range p {
+		b := p[(i+1)%len(p)]
+		l := math.Hypot(b.X-a.X, b.Y-a.Y)
+		if l == 0 {
+			continue
+		}
+		// inward normal is to the left of edge when area is positive
+		nx, ny := -(b.Y-a.Y)/l*sign, (b.X-a.X)/l*sign
+		q = q.ClipHalfPlane(nx, ny, nx*a.X+ny*a.Y+d)
+		if len(q) < 3 {
+			return nil
+		}
+	}
+	return q
+}
+
+// ClipHalfPlane keeps part of convex polygon where a*x + b*y >= c.
+func (p Polygon) ClipHalfPlane(a, b, c float64) Polygon {
+	if len(p) == 0 {
+		return nil
+	}
+	var q Polygon
+	for i, u := range p {
+		v := p[(i+1)%len(p)]
+		du, dv := a*u.X+b*u.Y-c, a*v.X+b*v.Y-c
+		if du >= 0 {
+			q = append(q, u)
+		}
+		if (du >= 0) != (dv >= 0) {
+			t := du / (du - dv)
+			q = append(q, Point{X: u.X + t*(v.X-u.X), Y: u.Y + t*(v.Y-u.Y)})
+		}
+	}
+	if len(q) < 3 {
+		return nil
+	}
+	return q
+}
+
+// Chord is where horizontal line at y crosses polygon.
+func (p Polygon) Chord(y float64) (x0, x1 float64, ok bool) {
+	x0, x1 = math.Inf(1), math.Inf(-1)
+	for i, u := range p {
+		v := p[(i+1)%len(p)]
+		if (u.Y > y) == (v.Y > y) {
+			continue
+		}
+		x := u.X + (y-u.Y)/(v.Y-u.Y)*(v.X-u.X)
+		x0, x1 = math.Min(x0, x), math.Max(x1, x)
+	}
+	if x1 < x0 {
+		return 0, 0, false
+	}
+	return x0, x1, true
+}
@@ -0,0 +1,169 @@
+package layout
+
+import (
+	"context"
+	"math"
+	"sort"
+)
+
+// Stable partitions box into parts with given areas, keeping them close to hints.
+// Hints are previous boxes of same parts, in same coordinates as box. Parts without hint have zero-value box.
+// Cuts that separate hints are found recursively and box is cut same way in proportion to new areas,
+// so that with same areas previous layout is made again, and changed areas move only boxes next to them.
+// This works for layouts made by cuts, like all layouts of this package.
+// Parts without hints are placed by layout at the end of longer side of box, or by Binary if layout is not set.
+// Without any hints this is same as layout.
+// Returns boxes in same order as areas.
+// Zero areas will have zero-value box.
+func Stable(ctx context.Context, box Box, areas []float64, hints []Box, layout Layout) []Box {
+	indices, cleanAreas := nonZeroAreas(ctx, box, areas)
+
+	var hinted, unhinted []hintedArea
+	for i, idx := range indices {
+		v := hintedArea{i: i, area: cleanAreas[i]}
+		if idx < len(hints) && hints[idx] != NilBox {
+			v.hint = hints[idx]
+			hinted = append(hinted, v)
+		} else {
+			unhinted = append(unhinted, v)
+		}
+	}
+
+	var hintedTotal, unhintedTotal float64
+	for _, v := range hinted {
+		hintedTotal += v.area
+	}
+	for _, v := range unhinted {
+		unhintedTotal += v.area
+	}
+
+	boxes := make([]Box, len(indices))
+	hintedBox, unhintedBox := splitBox(box, hintedTotal, unhintedTotal, box.W >= box.H)
+	if len(hinted) > 0 {
+		stableSplit(hintedBox, hinted, boxes, 1e-6*math.Max(box.W, box.H))
+	}
+	if len(unhinted) > 0 {
+		unhintedAreas := make([]float64, len(unhinted))
+		for i, v := range unhinted {
+			unhintedAreas[i] = v.area
+		}
+		if layout == nil {
+			layout = Binary
+		}
+		for i, b := range layout(ctx, unhintedBox, unhintedAreas) {
+			boxes[unhinted[i].i] = b
+		}
+	}
+	cutoffOverflows(ctx, box, boxes)
+
+	return restoreOrder(len(areas), indices, boxes)
+}
+
+type hintedArea struct {
+	i    int
+	area float64
+	hint Box
+}
+
+// stableSplit cuts box same way as hints are cut and sets boxes by position of item in non zero areas.
+func stableSplit(box Box, items []hintedArea, boxes []Box, eps float64) {
+	if len(items) == 0 {
+		return
+	}
+	if len(items) == 1 {
+		boxes[items[0].i] = box
+		return
+	}
+
+	k, vertical := findCut(items, eps)
+
+	var left, right float64
+	for _, v := range items[:k] {
+		left += v.area
+	}
+	for _, v := range items[k:] {
+		right += v.area
+	}
+	a, b := splitBox(box, left, right, vertical)
+
+	stableSplit(a, items[:k], boxes, eps)
+	stableSplit(b, items[k:], boxes, eps)
+}
+
+// findCut orders items and finds position of line that separates their hints.
+// If hints overlap and there is no such line, then items are split by centers of hints along longer side.
+func findCut(items []hintedArea, eps float64) (k int, vertical bool) {
+	for _, vertical := range []bool{true, false} {
+		sortHints(items, vertical)
+		maxEnd := math.Inf(-1)
+		for k := 1; k < len(items); k++ {
+			a, b := items[k-1].hint, items[k].hint
+			if vertical {
+				maxEnd = math.Max(maxEnd, a.X+a.W)
+				if maxEnd <= b.X+eps {
+					return k, true
+				}
+			} else {
+				maxEnd = math.Max(maxEnd, a.Y+a.H)
+				if maxEnd <= b.Y+eps {
+					return k, false
+				}
+			}
+		}
+	}
+
+	var minX, maxX, minY, maxY float64
+	for i, v := range items {
+		if i == 0 || v.hint.X < minX {
+			minX = v.hint.X
+		}
+		if i == 0 || v.hint.X+v.hint.W > maxX {
+			maxX = v.hint.X + v.hint.W
+		}
+		if i == 0 || v.hint.Y < minY {
+			minY = v.hint.Y
+		}
+		if i == 0 || v.hint.Y+v.hint.H > maxY {
+			maxY = v.hint.Y + v.hint.H
+		}
+	}
+	vertical = (maxX - minX) >= (maxY - minY)
+	sortHints(items, vertical)
+	return len(items) / 2, vertical
+}
+
+// sortHints orders items by start of hint, then by center, along x if vertical and along y otherwise.
+func sortHints(items []hintedArea, vertical bool) {
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i].hint, items[j].hint
+		if vertical {
+			if a.X != b.X {
+				return a.X < b.X
+			}
+			return a.X+a.W/2 < b.X+b.W/2
+		}
+		if a.Y != b.Y {
+			return a.Y < b.Y
+		}
+		return a.Y+a.H/2 < b.Y+b.H/2
+	})
+}
+
+// splitBox cuts box in two parts in proportion to areas, left and right if vertical, top and bottom otherwise.
+func splitBox(box Box, left, right float64, vertical bool) (a, b Box) {
+	total := left + right
+	if total == 0 {
+		return box, Box{}
+	}
+	a, b = box, box
+	if vertical {
+		a.W = box.W * left / total
+		b.X = box.X + a.W
+		b.W = box.W - a.W
+	} else {
+		a.H = box.H * left / total
+		b.Y = box.Y + a.H
+		b.H = box.H - a.H
+	}
+	return a, b
+}
@@ -0,0 +1,148 @@
+package layout
+
+import (
+	"context"
+	"math"
+)
+
+const (
+	voronoiMaxIterations = 200
+	voronoiMaxAreaError  = 0.01
+)
+
+// Voronoi partitions convex container into cells of weighted Voronoi diagram, which is power diagram, with given areas.
+// Sites are moved to centroids of their cells and weights are adapted to areas iteratively,
+// until error of areas is small, as described in "Computing Voronoi Treemaps", Arlind Nocaj and Ulrik Brandes, 2012
+// Initial sites are placed deterministically in order of areas, so same areas give same cells.
+// Returns polygons in same order as areas.
+// Zero areas will have nil polygon.
+func Voronoi(ctx context.Context, container Polygon, areas []float64) []Polygon {
+	res := make([]Polygon, len(areas))
+
+	total := container.Area()
+	if total == 0 || len(areas) == 0 {
+		return res
+	}
+
+	indices, targets := nonZeroPolygonAreas(ctx, areas, total)
+	if len(indices) == 0 {
+		return res
+	}
+	if len(indices) == 1 {
+		res[indices[0]] = container
+		return res
+	}
+
+	sites := initialSites(container, len(targets))
+	weights := make([]float64, len(targets))
+	for i := range weights {
+		weights[i] = total / float64(len(targets)) / 100
+	}
+
+	cells := powerCells(container, sites, weights)
+	for iter := 0; iter < voronoiMaxIterations && areaError(cells, targets, total) > voronoiMaxAreaError; iter++ {
+		for i, cell := range cells {
+			if len(cell) >= 3 {
+				sites[i] = cell.Centroid()
+			}
+		}
+		for i, cell := range cells {
+			a := cell.Area()
+			if a < total*1e-9 {
+				a = total * 1e-9
+			}
+			weights[i] = math.Max(weights[i]*targets[i]/a, total*1e-9)
+		}
+		limitWeights(sites, weights)
+		cells = powerCells(container, sites, weights)
+	}
+
+	for i, idx := range indices {
+		res[idx] = cells[i]
+	}
+	return res
+}
+
+// nonZeroPolygonAreas normalizes areas to total and returns non zero ones with their positions.
+func nonZeroPolygonAreas(ctx context.Context, areas []float64, total float64) (indices []int, targets []float64) {
+	for i, s := range normalizeAreas(ctx, areas, total) {
+		if s > 0 {
+			indices = append(indices, i)
+			targets = append(targets, s)
+		}
+	}
+	return indices, targets
+}
+
+// initialSites are on sunflower spiral inside largest circle around centroid that fits into container.
+func initialSites(container Polygon, n int) []Point {
+	c := container.Centroid()
+
+	r := math.Inf(1)
+	for i, a := range container {
+		b := container[(i+1)%len(container)]
+		l := math.Hypot(b.X-a.X, b.Y-a.Y)
+		if l == 0 {
+			continue
+		}
+		d := math.Abs((b.X-a.X)*(a.Y-c.Y)-(a.X-c.X)*(b.Y-a.Y)) / l
+		r = math.Min(r, d)
+	}
+	r *= 0.9
+
+	golden := math.Pi * (3 - math.Sqrt(5))
+	sites := make([]Point, n)
+	for i := range sites {
+		d := r * math.Sqrt((float64(i)+0.5)/float64(n))
+		a := golden * float64(i)
+		sites[i] = Point{X: c.X + d*math.Cos(a), Y: c.Y + d*math.Sin(a)}
+	}
+	return sites
+}
+
+// powerCells clips container by half-planes of power diagram of each site.
+// Point p is in cell of i if |p-si|^2 - wi <= |p-sj|^2 - wj for all j.
+func powerCells(container Polygon, sites []Point, weights []float64) []Polygon {
+	cells := make([]Polygon, len(sites))
+	for i, si := range sites {
+		cell := container
+		for j, sj := range sites {
+			if i == j {
+				continue
+			}
+			// 2 p.(sj - si) <= |sj|^2 - |si|^2 - wj + wi
+			a, b := -2*(sj.X-si.X), -2*(sj.Y-si.Y)
+			c := -((sj.X*sj.X + sj.Y*sj.Y) - (si.X*si.X + si.Y*si.Y) - weights[j] + weights[i])
+			if cell = cell.ClipHalfPlane(a, b, c); cell == nil {
+				break
+			}
+		}
+		cells[i] = cell
+	}
+	return cells
+}
+
+// limitWeights lowers weights so that each site stays in its own cell.
+// Site i is outside of its cell if wi - wj > |si-sj|^2 for some j.
+func limitWeights(sites []Point, weights []float64) {
+	for i := range sites {
+		for j := range sites {
+			if i == j {
+				continue
+			}
+			dx, dy := sites[i].X-sites[j].X, sites[i].Y-sites[j].Y
+			if d := dx*dx + dy*dy; weights[i]-weights[j] > d {
+				weights[i] = weights[j] + 0.9*d
+			}
+		}
+	}
+}
+
+// areaError is sum of differences of areas of cells to targets relative to total area.
+func areaError(cells []Polygon, targets []float64, total float64) float64 {
+	var e float64
+	for i, cell := range cells {
+		e += math.Abs(cell.Area() - targets[i])
+	}
+	return e / total / 2
+}
@@ -6,25 +6,30 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"math"
 	"strconv"
 	"strings"
 
 	"github.com/nikolaydubina/go-instrument-example/treemap"
 )
 
-// if duplicates, then sum size
-// if duplicates, then max heat
-// TODO: policies for duplicates
-type CSVTreeParser struct{}
+// CSVTreeParser parses rows of path, size and heat.
+// If HasHeader, then first row names columns, and they can be in any order.
+// Columns other than path, size and heat are kept as node attributes.
+// Without header, extra columns are kept as attributes by their index.
+// Empty size or heat cell means value is not set.
+// Name of node is last part of its path.
+type CSVTreeParser struct {
+	HasHeader       bool
+	DuplicatePolicy DuplicatePolicy
+}
 
 func (s CSVTreeParser) ParseString(ctx context.Context, in string) (tree *treemap.Tree, err error) {
-	nodes, err := parseNodes(ctx, in)
+	nodes, err := s.parseNodes(ctx, in)
 	if err != nil {
 		return nil, fmt.Errorf("can not parse nodes: %w", err)
 	}
 
-	tree, err = makeTree(ctx, nodes)
+	tree, err = makeTree(ctx, nodes, s.DuplicatePolicy)
 	if err != nil {
 		return nil, fmt.Errorf("can not make tree: %w", err)
 	}
@@ -32,9 +37,52 @@ func (s CSVTreeParser) ParseString(ctx context.Context, in string) (tree *treema
 	return tree, nil
 }
 
-func parseNodes(ctx context.Context, in string) (node []treemap.Node, err error) {
+// csvColumns is indexes of columns, -1 if column is not present.
+type csvColumns struct {
+	path       int
+	size       int
+	heat       int
+	attributes map[int]string
+}
+
+var defaultCSVColumns = csvColumns{path: 0, size: 1, heat: 2}
+
+func newCSVColumns(header []string) (csvColumns, error) {
+	columns := csvColumns{path: -1, size: -1, heat: -1, attributes: map[int]string{}}
+	for i, name := range header {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "path":
+			columns.path = i
+		case "size":
+			columns.size = i
+		case "heat":
+			columns.heat = i
+		default:
+			columns.attributes[i] = strings.TrimSpace(name)
+		}
+	}
+	if columns.path < 0 {
+		return columns, errors.New("no path column in header")
+	}
+	return columns, nil
+}
+
+func (s CSVTreeParser) parseNodes(ctx context.Context, in string) (node []treemap.Node, err error) {
 	var nodes []treemap.Node
 	r := csv.NewReader(strings.NewReader(in))
+	r.FieldsPerRecord = -1
+
+	columns := defaultCSVColumns
+	if s.HasHeader {
+		header, err := r.Read()
+		if err != nil {
+			return nil, fmt.Errorf("can not parse header: %w", err)
+		}
+		if columns, err = newCSVColumns(header); err != nil {
+			return nil, err
+		}
+	}
+
 	for {
 		record, err := r.Read()
 		if err == io.EOF {
@@ -44,108 +92,51 @@ func parseNodes(ctx context.Context, in string) (node []treemap.Node, err error)
 			return nil, fmt.Errorf("can not parse: %w", err)
 		}
 
-		if len(record) == 0 {
+		if len(record) == 0 || columns.path >= len(record) {
 			return nil, errors.New("no values in row")
 		}
 
-		node := treemap.Node{Path: record[0]}
+		node := treemap.Node{Path: record[columns.path]}
 
-		if len(record) >= 2 {
-			v, err := strconv.ParseFloat(record[1], 64)
+		if v := cell(record, columns.size); v != "" {
+			size, err := strconv.ParseFloat(v, 64)
 			if err != nil {
-				return nil, fmt.Errorf("size(%s) is not float: %w", record[1], err)
+				return nil, fmt.Errorf("size(%s) is not float: %w", v, err)
 			}
-			node.Size = v
+			node.Size = size
 		}
 
-		if len(record) >= 3 {
-			v, err := strconv.ParseFloat(record[2], 64)
+		if v := cell(record, columns.heat); v != "" {
+			heat, err := strconv.ParseFloat(v, 64)
 			if err != nil {
-				return nil, fmt.Errorf("heat(%s) is not float: %w", record[2], err)
+				return nil, fmt.Errorf("heat(%s) is not float: %w", v, err)
 			}
-			node.Heat = v
+			node.Heat = heat
 			node.HasHeat = true
 		}
 
-		nodes = append(nodes, node)
-	}
-	return nodes, nil
-}
-
-// If node is in path, but not present, then it will be in To but not will have entry in Nodes.
-// This is not terribly efficient, but should do its job for small graphs.
-func makeTree(ctx context.Context, nodes []treemap.Node) (*treemap.Tree, error) {
-	tree := treemap.Tree{
-		Nodes: map[string]treemap.Node{},
-		To:    map[string][]string{},
-	}
-
-	// for finding roots
-	hasParent := map[string]bool{}
-
-	for _, node := range nodes {
-		if existingNode, ok := tree.Nodes[node.Path]; ok {
-			tree.Nodes[node.Path] = treemap.Node{
-				Path:    existingNode.Path,
-				Name:    existingNode.Name,
-				Size:    existingNode.Size + node.Size,
-				Heat:    math.Max(existingNode.Heat, node.Heat),
-				HasHeat: existingNode.HasHeat || node.HasHeat,
+		for i, v := range record {
+			if i == columns.path || i == columns.size || i == columns.heat {
+				continue
 			}
-		}
-		tree.Nodes[node.Path] = node
-
-		parts := strings.Split(node.Path, "/")
-		hasParent[parts[0]] = false
-
-		for parent, i := parts[0], 1; i < len(parts); i++ {
-			child := parent + "/" + parts[i]
-
-			if _, ok := tree.Nodes[parent]; !ok {
-				tree.Nodes[parent] = treemap.Node{
-					Path:    parent,
-					HasHeat: false,
-				}
+			name, ok := columns.attributes[i]
+			if !ok {
+				name = strconv.Itoa(i)
 			}
-			tree.To[parent] = append(tree.To[parent], child)
-			hasParent[child] = true
-
-			parent = child
-		}
-	}
-
-	for node, v := range tree.To {
-		tree.To[node] = unique(v)
-	}
-
-	var roots []string
-	for node, has := range hasParent {
-		if !has {
-			roots = append(roots, node)
+			if node.Attributes == nil {
+				node.Attributes = map[string]string{}
+			}
+			node.Attributes[name] = v
 		}
-	}
 
-	switch {
-	case len(roots) == 0:
-		return nil, errors.New("no roots, possible cycle in graph")
-	case len(roots) > 1:
-		tree.Root = "some-secret-string"
-		tree.To[tree.Root] = roots
-	default:
-		tree.Root = roots[0]
+		nodes = append(nodes, node)
 	}
-
-	return &tree, nil
+	return nodes, nil
 }
 
-func unique(a []string) []string {
-	u := map[string]bool{}
-	var b []string
-	for _, q := range a {
-		if _, ok := u[q]; !ok {
-			u[q] = true
-			b = append(b, q)
-		}
+func cell(record []string, i int) string {
+	if i < 0 || i >= len(record) {
+		return ""
 	}
-	return b
+	return strings.TrimSpace(record[i])
 }
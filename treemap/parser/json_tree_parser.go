@@ -0,0 +1,206 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/nikolaydubina/go-instrument-example/treemap"
+)
+
+// JSONTreeParser parses nested nodes in d3-hierarchy shape.
+// Each node is object with name, size, heat and children fields.
+// Input is single root object or array of root objects.
+// Path of node is names of it and its parents joined by "/".
+// Names can have any characters, "%" and "/" in them are escaped in path as "%25" and "%2F", so that paths do not collide.
+// Name of node is kept as is, so tree is same as from CSV with such paths.
+// Other fields are kept as node attributes.
+type JSONTreeParser struct {
+	DuplicatePolicy DuplicatePolicy
+}
+
+func (s JSONTreeParser) ParseString(ctx context.Context, in string) (tree *treemap.Tree, err error) {
+	var roots []jsonNode
+
+	data := bytes.TrimSpace([]byte(in))
+	if len(data) > 0 && data[0] == '[' {
+		err = json.Unmarshal(data, &roots)
+	} else {
+		var root jsonNode
+		err = json.Unmarshal(data, &root)
+		roots = []jsonNode{root}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can not parse: %w", err)
+	}
+
+	tree = &treemap.Tree{
+		Nodes: map[string]treemap.Node{},
+		To:    map[string][]string{},
+	}
+
+	// for finding roots
+	hasParent := map[string]bool{}
+
+	merger, err := newNodeMerger(s.DuplicatePolicy)
+	if err != nil {
+		return nil, fmt.Errorf("can not make tree: %w", err)
+	}
+
+	for _, root := range roots {
+		if err := addJSONNode(tree, merger, root, ""); err != nil {
+			return nil, fmt.Errorf("can not make tree: %w", err)
+		}
+		hasParent[escapeName(root.Name)] = false
+	}
+
+	if err := treemap.SetRoot(tree, hasParent); err != nil {
+		return nil, fmt.Errorf("can not make tree: %w", err)
+	}
+
+	return tree, nil
+}
+
+// addJSONNode adds node and its children, parent is empty for roots.
+func addJSONNode(tree *treemap.Tree, merger nodeMerger, n jsonNode, parent string) error {
+	if n.Name == "" {
+		return errors.New("node without name")
+	}
+
+	path := escapeName(n.Name)
+	if parent != "" {
+		path = parent + "/" + path
+		tree.To[parent] = append(tree.To[parent], path)
+	}
+
+	node := n.node()
+	node.Path = path
+	if err := merger.add(tree, node); err != nil {
+		return err
+	}
+
+	for _, child := range n.Children {
+		if err := addJSONNode(tree, merger, child, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var nameEscaper = strings.NewReplacer("%", "%25", "/", "%2F")
+
+// escapeName makes name part of path.
+func escapeName(name string) string { return nameEscaper.Replace(name) }
+
+// JSONLTreeParser parses flat records, one JSON object per line.
+// Each record has path, size and heat fields, same as columns in CSV.
+// Other fields are kept as node attributes.
+type JSONLTreeParser struct {
+	DuplicatePolicy DuplicatePolicy
+}
+
+func (s JSONLTreeParser) ParseString(ctx context.Context, in string) (tree *treemap.Tree, err error) {
+	var nodes []treemap.Node
+
+	scanner := bufio.NewScanner(strings.NewReader(in))
+	scanner.Buffer(nil, 1<<20)
+	for i := 1; scanner.Scan(); i++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var record jsonNode
+		if err := json.Unmarshal(line, &record); err != nil {
+			return nil, fmt.Errorf("can not parse line(%d): %w", i, err)
+		}
+		if record.Path == "" {
+			return nil, fmt.Errorf("no path in line(%d)", i)
+		}
+
+		node := record.node()
+		node.Path = record.Path
+		nodes = append(nodes, node)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("can not parse: %w", err)
+	}
+
+	tree, err = makeTree(ctx, nodes, s.DuplicatePolicy)
+	if err != nil {
+		return nil, fmt.Errorf("can not make tree: %w", err)
+	}
+
+	return tree, nil
+}
+
+// jsonNode is either nested node or flat record.
+type jsonNode struct {
+	Name       string
+	Path       string
+	Size       *float64
+	Heat       *float64
+	Children   []jsonNode
+	Attributes map[string]string
+}
+
+func (s *jsonNode) UnmarshalJSON(data []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	if fields == nil {
+		return errors.New("node is null")
+	}
+
+	for k, v := range fields {
+		var err error
+		switch k {
+		case "name":
+			err = json.Unmarshal(v, &s.Name)
+		case "path":
+			err = json.Unmarshal(v, &s.Path)
+		case "size":
+			err = json.Unmarshal(v, &s.Size)
+		case "heat":
+			err = json.Unmarshal(v, &s.Heat)
+		case "children":
+			err = json.Unmarshal(v, &s.Children)
+		default:
+			if s.Attributes == nil {
+				s.Attributes = map[string]string{}
+			}
+			// strings are kept without quotes, rest is kept as raw JSON
+			var str string
+			if json.Unmarshal(v, &str) == nil {
+				s.Attributes[k] = str
+			} else {
+				s.Attributes[k] = string(v)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("bad field(%s): %w", k, err)
+		}
+	}
+
+	return nil
+}
+
+func (s jsonNode) node() treemap.Node {
+	node := treemap.Node{
+		Name:       s.Name,
+		Attributes: s.Attributes,
+	}
+	if s.Size != nil {
+		node.Size = *s.Size
+	}
+	if s.Heat != nil {
+		node.Heat = *s.Heat
+		node.HasHeat = true
+	}
+	return node
+}
@@ -0,0 +1,178 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/nikolaydubina/go-instrument-example/treemap"
+)
+
+// DuplicatePolicy is how size and heat of rows with same path are merged.
+type DuplicatePolicy string
+
+const (
+	// DuplicateDefault sums sizes and takes max heat.
+	DuplicateDefault DuplicatePolicy = ""
+	DuplicateSum     DuplicatePolicy = "sum"
+	DuplicateMax     DuplicatePolicy = "max"
+	DuplicateMin     DuplicatePolicy = "min"
+	DuplicateMean    DuplicatePolicy = "mean"
+	DuplicateLast    DuplicatePolicy = "last"
+	DuplicateError   DuplicatePolicy = "error"
+)
+
+// DuplicatePolicies are all policies, default policy is empty.
+var DuplicatePolicies = []DuplicatePolicy{DuplicateDefault, DuplicateSum, DuplicateMax, DuplicateMin, DuplicateMean, DuplicateLast, DuplicateError}
+
+// ParseDuplicatePolicy returns policy by name, empty name is default policy.
+func ParseDuplicatePolicy(name string) (DuplicatePolicy, error) {
+	for _, policy := range DuplicatePolicies {
+		if DuplicatePolicy(name) == policy {
+			return policy, nil
+		}
+	}
+	return DuplicateDefault, fmt.Errorf("unknown duplicate policy(%s), expected one of %v", name, DuplicatePolicies[1:])
+}
+
+// If node is in path, but not present, then it will be in To but not will have entry in Nodes.
+// Name of node is last part of its path, unless it is set.
+// This is not terribly efficient, but should do its job for small graphs.
+func makeTree(ctx context.Context, nodes []treemap.Node, policy DuplicatePolicy) (*treemap.Tree, error) {
+	tree := treemap.Tree{
+		Nodes: map[string]treemap.Node{},
+		To:    map[string][]string{},
+	}
+
+	// for finding roots
+	hasParent := map[string]bool{}
+
+	merger, err := newNodeMerger(policy)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, node := range nodes {
+		if err := merger.add(&tree, node); err != nil {
+			return nil, err
+		}
+
+		treemap.AddPath(&tree, hasParent, node.Path)
+	}
+
+	if err := treemap.SetRoot(&tree, hasParent); err != nil {
+		return nil, err
+	}
+
+	// names are same as in nested JSON, where each node has name
+	treemap.SetNamesFromPaths(ctx, &tree)
+
+	return &tree, nil
+}
+
+// nodeMerger adds nodes to tree and merges duplicates.
+// It counts rows and values merged into each node, parents that are only in paths are not counted.
+// Zero size is not set, same as in size imputer.
+type nodeMerger struct {
+	policy    DuplicatePolicy
+	rowCount  map[string]int
+	sizeCount map[string]int
+	heatCount map[string]int
+}
+
+func newNodeMerger(policy DuplicatePolicy) (nodeMerger, error) {
+	if _, err := ParseDuplicatePolicy(string(policy)); err != nil {
+		return nodeMerger{}, err
+	}
+	return nodeMerger{
+		policy:    policy,
+		rowCount:  map[string]int{},
+		sizeCount: map[string]int{},
+		heatCount: map[string]int{},
+	}, nil
+}
+
+func (s nodeMerger) add(tree *treemap.Tree, node treemap.Node) error {
+	if s.rowCount[node.Path] > 0 {
+		merged, err := mergeNodes(tree.Nodes[node.Path], node, s.policy, s.sizeCount[node.Path], s.heatCount[node.Path])
+		if err != nil {
+			return err
+		}
+		tree.Nodes[node.Path] = merged
+	} else {
+		tree.Nodes[node.Path] = node
+	}
+	s.rowCount[node.Path]++
+	if node.Size != 0 {
+		s.sizeCount[node.Path]++
+	}
+	if node.HasHeat {
+		s.heatCount[node.Path]++
+	}
+	return nil
+}
+
+// mergeNodes merges duplicate node into existing one.
+// Counts are number of sizes and heats already merged into existing node, rows without them are not counted.
+// Attributes of duplicate override attributes of existing node.
+func mergeNodes(existing, node treemap.Node, policy DuplicatePolicy, sizeCount, heatCount int) (treemap.Node, error) {
+	if policy == DuplicateError {
+		return treemap.Node{}, fmt.Errorf("duplicate node(%s)", node.Path)
+	}
+
+	merged := treemap.Node{
+		Path:       existing.Path,
+		Name:       existing.Name,
+		Size:       existing.Size,
+		Heat:       existing.Heat,
+		HasHeat:    existing.HasHeat || node.HasHeat,
+		Attributes: existing.Attributes,
+	}
+
+	switch {
+	case sizeCount > 0 && node.Size != 0:
+		merged.Size = mergeValues(policy, existing.Size, node.Size, sizeCount, false)
+	case node.Size != 0:
+		merged.Size = node.Size
+	}
+
+	switch {
+	case existing.HasHeat && node.HasHeat:
+		merged.Heat = mergeValues(policy, existing.Heat, node.Heat, heatCount, true)
+	case node.HasHeat:
+		merged.Heat = node.Heat
+	}
+
+	if len(node.Attributes) > 0 {
+		merged.Attributes = make(map[string]string, len(existing.Attributes)+len(node.Attributes))
+		for k, v := range existing.Attributes {
+			merged.Attributes[k] = v
+		}
+		for k, v := range node.Attributes {
+			merged.Attributes[k] = v
+		}
+	}
+
+	return merged, nil
+}
+
+// mergeValues merges value into accumulated value of n values.
+func mergeValues(policy DuplicatePolicy, acc, v float64, n int, isHeat bool) float64 {
+	switch policy {
+	case DuplicateSum:
+		return acc + v
+	case DuplicateMax:
+		return math.Max(acc, v)
+	case DuplicateMin:
+		return math.Min(acc, v)
+	case DuplicateMean:
+		return (acc*float64(n) + v) / float64(n+1)
+	case DuplicateLast:
+		return v
+	default:
+		if isHeat {
+			return math.Max(acc, v)
+		}
+		return acc + v
+	}
+}
@@ -0,0 +1,140 @@
+package treemap
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// PathPattern matches path by glob, or by regular expression if it has `re:` prefix.
+// Glob matches any trailing segments of path, so `*_mock.go` matches file in any directory
+// and `internal/testutil/**` matches everything under such directory at any depth.
+// Regular expression is not anchored and is matched against whole path.
+type PathPattern struct {
+	glob string
+	re   *regexp.Regexp
+}
+
+func ParsePathPattern(s string) (PathPattern, error) {
+	if strings.HasPrefix(s, "re:") {
+		expr := strings.TrimPrefix(s, "re:")
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return PathPattern{}, fmt.Errorf("can not compile regexp(%s): %w", expr, err)
+		}
+		return PathPattern{re: re}, nil
+	}
+	if s == "" {
+		return PathPattern{}, fmt.Errorf("empty pattern")
+	}
+	return PathPattern{glob: "**/" + strings.TrimPrefix(s, "/")}, nil
+}
+
+func (s PathPattern) Match(path string) bool {
+	if s.re != nil {
+		return s.re.MatchString(path)
+	}
+	return MatchGlob(s.glob, path)
+}
+
+// PathFilter keeps paths that match any of Include and do not match any of Exclude.
+// Empty Include keeps all paths.
+type PathFilter struct {
+	Include []PathPattern
+	Exclude []PathPattern
+}
+
+// NewPathFilter parses patterns.
+func NewPathFilter(include []string, exclude []string) (PathFilter, error) {
+	var s PathFilter
+	for _, v := range include {
+		p, err := ParsePathPattern(v)
+		if err != nil {
+			return s, fmt.Errorf("bad include: %w", err)
+		}
+		s.Include = append(s.Include, p)
+	}
+	for _, v := range exclude {
+		p, err := ParsePathPattern(v)
+		if err != nil {
+			return s, fmt.Errorf("bad exclude: %w", err)
+		}
+		s.Exclude = append(s.Exclude, p)
+	}
+	return s, nil
+}
+
+// IsEmpty is true when filter keeps all paths.
+func (s PathFilter) IsEmpty() bool { return len(s.Include) == 0 && len(s.Exclude) == 0 }
+
+func (s PathFilter) Keep(path string) bool {
+	if len(s.Include) > 0 {
+		included := false
+		for _, p := range s.Include {
+			if p.Match(path) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+	for _, p := range s.Exclude {
+		if p.Match(path) {
+			return false
+		}
+	}
+	return true
+}
+
+// FilterTree removes leaves that are not kept and parents that are left without children.
+// Leaves are matched by Path of node, so this works after collapsing long paths too.
+// Size and heat of parents that lost children are unset, so that they can be imputed again.
+func (s PathFilter) FilterTree(ctx context.Context, t *Tree) {
+	if t == nil || s.IsEmpty() {
+		return
+	}
+	s.filterNode(t, t.Root)
+}
+
+// filterNode returns whether node is removed and whether its subtree changed.
+func (s PathFilter) filterNode(t *Tree, node string) (removed bool, changed bool) {
+	children := t.To[node]
+	if len(children) == 0 {
+		if s.Keep(t.Nodes[node].Path) {
+			return false, false
+		}
+		delete(t.Nodes, node)
+		delete(t.To, node)
+		return true, true
+	}
+
+	var next []string
+	for _, child := range children {
+		childRemoved, childChanged := s.filterNode(t, child)
+		if !childRemoved {
+			next = append(next, child)
+		}
+		changed = changed || childChanged
+	}
+	if !changed {
+		return false, false
+	}
+
+	if len(next) == 0 && node != t.Root {
+		delete(t.Nodes, node)
+		delete(t.To, node)
+		return true, true
+	}
+
+	t.To[node] = next
+	if n, ok := t.Nodes[node]; ok {
+		n.Size = 0
+		n.Heat = 0
+		n.HasHeat = false
+		t.Nodes[node] = n
+	}
+	return false, true
+}
@@ -0,0 +1,77 @@
+package treemap
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// ArtificialRoot is path of root that groups multiple roots of tree, it has no name.
+const ArtificialRoot = "some-secret-string"
+
+// AddPath adds edges from each parent in path to its child, path is split by "/".
+// Parents that are not in tree are added without size and heat.
+// First part of path is marked as root in hasParent and others as having parent, so that SetRoot can find roots.
+func AddPath(tree *Tree, hasParent map[string]bool, path string) {
+	parts := strings.Split(path, "/")
+	if _, ok := hasParent[parts[0]]; !ok {
+		hasParent[parts[0]] = false
+	}
+
+	for parent, i := parts[0], 1; i < len(parts); i++ {
+		child := parent + "/" + parts[i]
+
+		if _, ok := tree.Nodes[parent]; !ok {
+			tree.Nodes[parent] = Node{
+				Path: parent,
+			}
+		}
+
+		tree.To[parent] = append(tree.To[parent], child)
+		hasParent[child] = true
+
+		parent = child
+	}
+}
+
+// SetRoot deduplicates edges and sets root of tree.
+// Roots are nodes that do not have parent in hasParent.
+// If there are multiple roots, then they are grouped under ArtificialRoot in order of paths,
+// so that same tree is drawn same way each time.
+func SetRoot(tree *Tree, hasParent map[string]bool) error {
+	for node, v := range tree.To {
+		tree.To[node] = unique(v)
+	}
+
+	var roots []string
+	for node, has := range hasParent {
+		if !has {
+			roots = append(roots, node)
+		}
+	}
+	sort.Strings(roots)
+
+	switch {
+	case len(roots) == 0:
+		return errors.New("no roots, possible cycle in graph")
+	case len(roots) > 1:
+		tree.Root = ArtificialRoot
+		tree.To[tree.Root] = roots
+	default:
+		tree.Root = roots[0]
+	}
+
+	return nil
+}
+
+func unique(a []string) []string {
+	u := map[string]bool{}
+	var b []string
+	for _, q := range a {
+		if _, ok := u[q]; !ok {
+			u[q] = true
+			b = append(b, q)
+		}
+	}
+	return b
+}
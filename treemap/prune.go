@@ -0,0 +1,112 @@
+package treemap
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// DepthLimiter cuts nodes deeper than MaxDepth, root is at depth 0.
+// Nodes at MaxDepth become leaves, so their size and heat have to be already imputed.
+type DepthLimiter struct {
+	MaxDepth int
+}
+
+func (s DepthLimiter) LimitDepth(ctx context.Context, t *Tree) {
+	if t == nil {
+		return
+	}
+	s.limitDepthNode(t, t.Root, 0)
+}
+
+func (s DepthLimiter) limitDepthNode(t *Tree, node string, depth int) {
+	if depth < s.MaxDepth {
+		for _, child := range t.To[node] {
+			s.limitDepthNode(t, child, depth+1)
+		}
+		return
+	}
+	for _, child := range t.To[node] {
+		removeSubtree(t, child)
+	}
+	delete(t.To, node)
+}
+
+// TopNPruner keeps N largest children of each node and folds rest into single `other (k items)` node.
+// Size of folded node is sum of sizes, and heat is size weighted heat of folded nodes.
+// Single remaining child is kept as is, since folding it does not make tree smaller.
+// Size and heat of nodes have to be already imputed.
+type TopNPruner struct {
+	N int
+}
+
+func (s TopNPruner) PruneTopN(ctx context.Context, t *Tree) {
+	if t == nil || s.N < 1 {
+		return
+	}
+	s.pruneTopNNode(t, t.Root)
+}
+
+func (s TopNPruner) pruneTopNNode(t *Tree, node string) {
+	children := t.To[node]
+
+	if len(children) > s.N+1 {
+		sorted := make([]string, len(children))
+		copy(sorted, children)
+		sort.SliceStable(sorted, func(i, j int) bool {
+			a, b := t.Nodes[sorted[i]], t.Nodes[sorted[j]]
+			if a.Size != b.Size {
+				return a.Size > b.Size
+			}
+			return sorted[i] < sorted[j]
+		})
+
+		kept, folded := sorted[:s.N], sorted[s.N:]
+
+		name := fmt.Sprintf("other (%d items)", len(folded))
+		other := Node{
+			Path: node + "/" + name,
+			Name: name,
+		}
+		var heat, heatSize float64
+		for _, child := range folded {
+			n := t.Nodes[child]
+			other.Size += n.Size
+			if n.HasHeat {
+				heat += n.Heat * n.Size
+				heatSize += n.Size
+				other.HasHeat = true
+			}
+			removeSubtree(t, child)
+		}
+		if heatSize > 0 {
+			other.Heat = heat / heatSize
+		}
+
+		// keep original order of children that are left
+		var next []string
+		for _, child := range children {
+			for _, k := range kept {
+				if child == k {
+					next = append(next, child)
+					break
+				}
+			}
+		}
+		t.Nodes[other.Path] = other
+		t.To[node] = append(next, other.Path)
+	}
+
+	for _, child := range t.To[node] {
+		s.pruneTopNNode(t, child)
+	}
+}
+
+// removeSubtree deletes node and all its descendants.
+func removeSubtree(t *Tree, node string) {
+	for _, child := range t.To[node] {
+		removeSubtree(t, child)
+	}
+	delete(t.To, node)
+	delete(t.Nodes, node)
+}
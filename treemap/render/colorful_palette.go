@@ -43,6 +43,9 @@ var paletteReBuCSV string
 //go:embed palettes/RdYlGn.csv
 var paletteRdYlGnCSV string
 
+//go:embed palettes/YlOrRd.csv
+var paletteYlOrRdCSV string
+
 func makePaletteFromCSV(ctx context.Context, csv string) ColorfulPalette {
 	rows := strings.Split(csv, "\n")
 	palette := make(ColorfulPalette, len(rows))
@@ -76,6 +79,8 @@ func GetPalette(ctx context.Context, name string) (ColorfulPalette, bool) {
 		return makePaletteFromCSV(ctx, paletteReBuCSV), true
 	case "RdYlGn":
 		return makePaletteFromCSV(ctx, paletteRdYlGnCSV), true
+	case "YlOrRd":
+		return makePaletteFromCSV(ctx, paletteYlOrRdCSV), true
 	default:
 		return nil, false
 	}
@@ -0,0 +1,201 @@
+package render
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"image/color"
+	"io"
+)
+
+// HTMLRenderer makes single self-contained HTML page with interactive treemap.
+// Boxes have tooltips with path, size and heat.
+// Clicking on box zooms into it, breadcrumbs navigate back to root.
+type HTMLRenderer struct{}
+
+func (r HTMLRenderer) Render(ctx context.Context, out io.Writer, root UIBox, w, h float64) error {
+	if !root.IsRoot {
+		return errors.New("box is not root")
+	}
+
+	b := bufio.NewWriter(out)
+
+	b.WriteString(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>treemap</title>
+<style>`)
+	b.WriteString(htmlStyle)
+	b.WriteString(`</style>
+</head>
+<body>
+<div id="breadcrumbs"></div>
+`)
+	fmt.Fprintf(b, `<svg id="treemap" xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %f %f" data-viewbox="0 0 %f %f" preserveAspectRatio="xMidYMid meet">`, w, h, w, h)
+	b.WriteString("\n")
+	boxHTML(ctx, b, root)
+	b.WriteString(`</svg>
+<div id="tooltip"></div>
+<script>`)
+	b.WriteString(htmlScript)
+	b.WriteString(`</script>
+</body>
+</html>
+`)
+
+	return b.Flush()
+}
+
+// boxHTML writes box and its children as nested groups, so that hierarchy is available to script.
+func boxHTML(ctx context.Context, b *bufio.Writer, q UIBox) {
+	if q.IsInvisible {
+		for _, child := range q.Children {
+			boxHTML(ctx, b, child)
+		}
+		return
+	}
+
+	heat := ""
+	if q.HasHeat {
+		heat = fmt.Sprintf("%.1f%%", q.Heat*100)
+	}
+
+	name := ""
+	if q.Title != nil {
+		name = q.Title.Text
+	}
+
+	fmt.Fprintf(b, `<g class="box" data-path="%s" data-name="%s" data-size="%g" data-heat="%s" data-x="%f" data-y="%f" data-w="%f" data-h="%f">`,
+		html.EscapeString(q.Path),
+		html.EscapeString(name),
+		q.Size,
+		heat,
+		q.X,
+		q.Y,
+		q.W,
+		q.H,
+	)
+	if len(q.Polygon) > 0 {
+		fmt.Fprintf(b, `<polygon points="%s" style="fill: %s; stroke: %s; stroke-width: %gpx;" />`,
+			svgPoints(q.Polygon),
+			cssColor(q.Color, color.White),
+			cssColor(q.BorderColor, color.White),
+			q.strokeWidth(),
+		)
+	} else {
+		fmt.Fprintf(b, `<rect x="%f" y="%f" width="%f" height="%f" style="fill: %s; stroke: %s; stroke-width: %gpx;" />`,
+			q.X,
+			q.Y,
+			q.W,
+			q.H,
+			cssColor(q.Color, color.White),
+			cssColor(q.BorderColor, color.White),
+			q.strokeWidth(),
+		)
+	}
+	if t := q.Title; t != nil {
+		for _, line := range t.lines() {
+			fmt.Fprintf(b, `<text transform="translate(%f,%f) scale(%f)" textLength="%f" lengthAdjust="spacingAndGlyphs" style="font-size: %dpx; fill: %s;">%s</text>`,
+				line.X,
+				line.Y,
+				t.Scale,
+				line.W/t.Scale,
+				fontSize,
+				cssColor(t.Color, color.Black),
+				html.EscapeString(line.Text),
+			)
+		}
+	}
+	b.WriteString("\n")
+	for _, child := range q.Children {
+		boxHTML(ctx, b, child)
+	}
+	b.WriteString("</g>\n")
+}
+
+const htmlStyle = `
+body { margin: 0; font-family: Open Sans, verdana, arial, sans-serif; background: white; }
+#breadcrumbs { padding: 4px 8px; font-size: 14px; }
+#breadcrumbs span { cursor: pointer; color: #2166ac; }
+#breadcrumbs span:last-child { cursor: default; color: black; }
+#treemap { display: block; width: 100vw; height: calc(100vh - 30px); }
+#treemap text { pointer-events: none; white-space: pre; }
+#treemap g.box { cursor: zoom-in; }
+#treemap g.box.hover > rect, #treemap g.box.hover > polygon { stroke: black; stroke-width: 2px; }
+#tooltip { position: fixed; display: none; pointer-events: none; padding: 4px 8px; background: rgba(255, 255, 255, 0.95); border: 1px solid grey; font-size: 12px; white-space: pre; }
+`
+
+const htmlScript = `
+(function () {
+	var svg = document.getElementById("treemap");
+	var tooltip = document.getElementById("tooltip");
+	var breadcrumbs = document.getElementById("breadcrumbs");
+	var hovered = null;
+	var focused = null;
+
+	function ancestors(g) {
+		var chain = [];
+		for (var q = g; q && q !== svg; q = q.parentNode) {
+			if (q.classList && q.classList.contains("box")) chain.unshift(q);
+		}
+		return chain;
+	}
+
+	function label(g) {
+		return g.dataset.name || g.dataset.path.split("/").pop() || "root";
+	}
+
+	function zoom(g) {
+		focused = g;
+		if (g) {
+			svg.setAttribute("viewBox", [g.dataset.x, g.dataset.y, g.dataset.w, g.dataset.h].join(" "));
+		} else {
+			svg.setAttribute("viewBox", svg.dataset.viewbox);
+		}
+		breadcrumbs.textContent = "";
+		var items = [null].concat(g ? ancestors(g) : []);
+		items.forEach(function (item, i) {
+			if (i > 0) breadcrumbs.appendChild(document.createTextNode(" / "));
+			var span = document.createElement("span");
+			span.textContent = item ? label(item) : "root";
+			span.addEventListener("click", function () { zoom(item); });
+			breadcrumbs.appendChild(span);
+		});
+	}
+
+	svg.addEventListener("mousemove", function (e) {
+		var g = e.target.closest ? e.target.closest("g.box") : null;
+		if (hovered && hovered !== g) hovered.classList.remove("hover");
+		hovered = g;
+		if (!g) {
+			tooltip.style.display = "none";
+			return;
+		}
+		g.classList.add("hover");
+		var lines = [g.dataset.path, "size: " + g.dataset.size];
+		if (g.dataset.heat) lines.push("heat: " + g.dataset.heat);
+		tooltip.textContent = lines.join("\n");
+		tooltip.style.display = "block";
+		tooltip.style.left = (e.clientX + 12) + "px";
+		tooltip.style.top = (e.clientY + 12) + "px";
+	});
+
+	svg.addEventListener("mouseleave", function () {
+		tooltip.style.display = "none";
+	});
+
+	// zooms one level deeper than current box towards clicked box
+	svg.addEventListener("click", function (e) {
+		var g = e.target.closest ? e.target.closest("g.box") : null;
+		if (!g) return;
+		var chain = ancestors(g);
+		var next = chain[chain.indexOf(focused) + 1];
+		if (next) zoom(next);
+	});
+
+	zoom(null);
+})();
+`
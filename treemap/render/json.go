@@ -0,0 +1,166 @@
+package render
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+
+	"github.com/nikolaydubina/go-instrument-example/treemap/layout"
+)
+
+// JSONRenderer dumps treemap spec as JSON.
+// Colors are hex strings in form of #rrggbbaa.
+type JSONRenderer struct{}
+
+type jsonUITreeMap struct {
+	W    float64   `json:"w"`
+	H    float64   `json:"h"`
+	Root jsonUIBox `json:"root"`
+}
+
+type jsonUITextLine struct {
+	Text string  `json:"text"`
+	X    float64 `json:"x"`
+	Y    float64 `json:"y"`
+	W    float64 `json:"w"`
+}
+
+type jsonUIText struct {
+	Text  string           `json:"text"`
+	Lines []jsonUITextLine `json:"lines,omitempty"`
+	X     float64          `json:"x"`
+	Y     float64          `json:"y"`
+	H     float64          `json:"h"`
+	W     float64          `json:"w"`
+	Scale float64          `json:"scale"`
+	Color string           `json:"color,omitempty"`
+}
+
+type jsonUIBox struct {
+	Path        string       `json:"path,omitempty"`
+	Size        float64      `json:"size"`
+	Heat        float64      `json:"heat"`
+	HasHeat     bool         `json:"has_heat,omitempty"`
+	Title       *jsonUIText  `json:"title,omitempty"`
+	X           float64      `json:"x"`
+	Y           float64      `json:"y"`
+	W           float64      `json:"w"`
+	H           float64      `json:"h"`
+	Children    []jsonUIBox  `json:"children,omitempty"`
+	IsInvisible bool         `json:"is_invisible,omitempty"`
+	IsRoot      bool         `json:"is_root,omitempty"`
+	Color       string       `json:"color,omitempty"`
+	BorderColor string       `json:"border_color,omitempty"`
+	BorderWidth float64      `json:"border_width,omitempty"`
+	Polygon     [][2]float64 `json:"polygon,omitempty"`
+}
+
+func (r JSONRenderer) Render(ctx context.Context, out io.Writer, root UIBox, w, h float64) error {
+	if !root.IsRoot {
+		return errors.New("box is not root")
+	}
+	return json.NewEncoder(out).Encode(jsonUITreeMap{
+		W:    w,
+		H:    h,
+		Root: newJSONUIBox(root),
+	})
+}
+
+func newJSONUIBox(q UIBox) jsonUIBox {
+	b := jsonUIBox{
+		Path:        q.Path,
+		Size:        q.Size,
+		Heat:        q.Heat,
+		HasHeat:     q.HasHeat,
+		X:           q.X,
+		Y:           q.Y,
+		W:           q.W,
+		H:           q.H,
+		IsInvisible: q.IsInvisible,
+		IsRoot:      q.IsRoot,
+		Color:       hexColor(q.Color),
+		BorderColor: hexColor(q.BorderColor),
+		BorderWidth: q.BorderWidth,
+	}
+	for _, p := range q.Polygon {
+		b.Polygon = append(b.Polygon, [2]float64{p.X, p.Y})
+	}
+	if t := q.Title; t != nil {
+		b.Title = &jsonUIText{
+			Text:  t.Text,
+			X:     t.X,
+			Y:     t.Y,
+			H:     t.H,
+			W:     t.W,
+			Scale: t.Scale,
+			Color: hexColor(t.Color),
+		}
+		for _, line := range t.Lines {
+			b.Title.Lines = append(b.Title.Lines, jsonUITextLine{Text: line.Text, X: line.X, Y: line.Y, W: line.W})
+		}
+	}
+	for _, child := range q.Children {
+		b.Children = append(b.Children, newJSONUIBox(child))
+	}
+	return b
+}
+
+func hexColor(c color.Color) string {
+	if c == nil {
+		return ""
+	}
+	r, g, b, a := c.RGBA()
+	return fmt.Sprintf("#%02x%02x%02x%02x", r>>8, g>>8, b>>8, a>>8)
+}
+
+// ParseJSONUITreeMapFromReader reads treemap spec made by JSONRenderer.
+// Colors are not parsed, as they are only needed to render treemap.
+func ParseJSONUITreeMapFromReader(r io.Reader) (UIBox, error) {
+	var spec jsonUITreeMap
+	if err := json.NewDecoder(r).Decode(&spec); err != nil {
+		return UIBox{}, fmt.Errorf("can not decode spec: %w", err)
+	}
+	if !spec.Root.IsRoot {
+		return UIBox{}, errors.New("box is not root")
+	}
+	return newUIBoxFromJSON(spec.Root), nil
+}
+
+func newUIBoxFromJSON(b jsonUIBox) UIBox {
+	q := UIBox{
+		Path:        b.Path,
+		Size:        b.Size,
+		Heat:        b.Heat,
+		HasHeat:     b.HasHeat,
+		X:           b.X,
+		Y:           b.Y,
+		W:           b.W,
+		H:           b.H,
+		IsInvisible: b.IsInvisible,
+		IsRoot:      b.IsRoot,
+		BorderWidth: b.BorderWidth,
+	}
+	for _, p := range b.Polygon {
+		q.Polygon = append(q.Polygon, layout.Point{X: p[0], Y: p[1]})
+	}
+	if t := b.Title; t != nil {
+		q.Title = &UIText{
+			Text:  t.Text,
+			X:     t.X,
+			Y:     t.Y,
+			H:     t.H,
+			W:     t.W,
+			Scale: t.Scale,
+		}
+		for _, line := range t.Lines {
+			q.Title.Lines = append(q.Title.Lines, UITextLine{Text: line.Text, X: line.X, Y: line.Y, W: line.W})
+		}
+	}
+	for _, child := range b.Children {
+		q.Children = append(q.Children, newUIBoxFromJSON(child))
+	}
+	return q
+}
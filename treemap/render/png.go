@@ -0,0 +1,224 @@
+package render
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+	"sort"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+
+	"github.com/nikolaydubina/go-instrument-example/treemap/layout"
+)
+
+// PNGRenderer draws treemap into raster image with same geometry as SVGRenderer.
+// Pixel is painted when its center is within shape, which is how SVG is rasterized without anti-aliasing.
+// Text is drawn with bundled Go font.
+type PNGRenderer struct{}
+
+func (r PNGRenderer) Render(ctx context.Context, out io.Writer, root UIBox, w, h float64) error {
+	img, err := r.Image(ctx, root, w, h)
+	if err != nil {
+		return err
+	}
+	return png.Encode(out, img)
+}
+
+// Image draws boxes in same order as SVGRenderer.
+func (r PNGRenderer) Image(ctx context.Context, root UIBox, w, h float64) (*image.RGBA, error) {
+	if !root.IsRoot {
+		return nil, errors.New("box is not root")
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, int(math.Ceil(w)), int(math.Ceil(h))))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+
+	fonts, err := newFontFaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer fonts.Close()
+
+	var q UIBox
+	que := []UIBox{root}
+	for len(que) > 0 {
+		q, que = que[0], que[1:]
+		que = append(que, q.Children...)
+		if err := drawBox(ctx, img, fonts, q); err != nil {
+			return nil, err
+		}
+	}
+
+	return img, nil
+}
+
+func drawBox(ctx context.Context, img *image.RGBA, fonts *fontFaces, q UIBox) error {
+	if q.IsInvisible {
+		return nil
+	}
+
+	var fill color.Color = color.White
+	if q.Color != nil {
+		fill = q.Color
+	}
+	var border color.Color = color.White
+	if q.BorderColor != nil {
+		border = q.BorderColor
+	}
+
+	if len(q.Polygon) > 0 {
+		fillPolygon(img, q.Polygon, fill)
+		strokePolygon(img, q.Polygon, q.strokeWidth(), border)
+		return drawText(ctx, img, fonts, q.Title)
+	}
+
+	fillRect(img, q.X, q.Y, q.X+q.W, q.Y+q.H, fill)
+
+	// stroke is centered on edges of box
+	sw := q.strokeWidth() / 2
+	fillRect(img, q.X-sw, q.Y-sw, q.X+q.W+sw, q.Y+sw, border)
+	fillRect(img, q.X-sw, q.Y+q.H-sw, q.X+q.W+sw, q.Y+q.H+sw, border)
+	fillRect(img, q.X-sw, q.Y+sw, q.X+sw, q.Y+q.H-sw, border)
+	fillRect(img, q.X+q.W-sw, q.Y+sw, q.X+q.W+sw, q.Y+q.H-sw, border)
+
+	return drawText(ctx, img, fonts, q.Title)
+}
+
+func drawText(ctx context.Context, img *image.RGBA, fonts *fontFaces, t *UIText) error {
+	if t == nil || t.Text == "" {
+		return nil
+	}
+
+	face, err := fonts.Face(float64(fontSize) * t.Scale)
+	if err != nil {
+		return err
+	}
+
+	var c color.Color = color.Black
+	if t.Color != nil {
+		c = t.Color
+	}
+
+	for _, line := range t.lines() {
+		d := font.Drawer{
+			Dst:  img,
+			Src:  image.NewUniform(c),
+			Face: face,
+			Dot:  fixed.Point26_6{X: floatToFixed(line.X), Y: floatToFixed(line.Y)},
+		}
+		d.DrawString(line.Text)
+	}
+	return nil
+}
+
+// fillRect paints pixels which centers are within rectangle.
+func fillRect(img *image.RGBA, x0, y0, x1, y1 float64, c color.Color) {
+	rect := image.Rect(
+		int(math.Ceil(x0-0.5)),
+		int(math.Ceil(y0-0.5)),
+		int(math.Ceil(x1-0.5)),
+		int(math.Ceil(y1-0.5)),
+	)
+	draw.Draw(img, rect.Intersect(img.Bounds()), image.NewUniform(c), image.Point{}, draw.Over)
+}
+
+// fillPolygon paints pixels which centers are within polygon by even-odd rule.
+func fillPolygon(img *image.RGBA, p layout.Polygon, c color.Color) {
+	b := p.Bounds()
+	src := image.NewUniform(c)
+	for y := int(math.Ceil(b.Y - 0.5)); float64(y)+0.5 <= b.Y+b.H; y++ {
+		xs := scanlineCrossings(p, float64(y)+0.5)
+		for i := 0; i+1 < len(xs); i += 2 {
+			row := image.Rect(int(math.Ceil(xs[i]-0.5)), y, int(math.Ceil(xs[i+1]-0.5)), y+1)
+			draw.Draw(img, row.Intersect(img.Bounds()), src, image.Point{}, draw.Over)
+		}
+	}
+}
+
+// scanlineCrossings are sorted x where horizontal line at y crosses edges of polygon.
+func scanlineCrossings(p layout.Polygon, y float64) []float64 {
+	var xs []float64
+	for i, u := range p {
+		v := p[(i+1)%len(p)]
+		if (u.Y > y) == (v.Y > y) {
+			continue
+		}
+		xs = append(xs, u.X+(y-u.Y)/(v.Y-u.Y)*(v.X-u.X))
+	}
+	sort.Float64s(xs)
+	return xs
+}
+
+// strokePolygon paints pixels which centers are within half of width from edges of polygon.
+func strokePolygon(img *image.RGBA, p layout.Polygon, width float64, c color.Color) {
+	sw := width / 2
+	for i, u := range p {
+		v := p[(i+1)%len(p)]
+		x0, x1 := int(math.Floor(math.Min(u.X, v.X)-sw)), int(math.Ceil(math.Max(u.X, v.X)+sw))
+		y0, y1 := int(math.Floor(math.Min(u.Y, v.Y)-sw)), int(math.Ceil(math.Max(u.Y, v.Y)+sw))
+		for y := y0; y <= y1; y++ {
+			for x := x0; x <= x1; x++ {
+				if !(image.Point{X: x, Y: y}).In(img.Bounds()) {
+					continue
+				}
+				if segmentDistance(float64(x)+0.5, float64(y)+0.5, u, v) <= sw {
+					img.Set(x, y, c)
+				}
+			}
+		}
+	}
+}
+
+// segmentDistance is distance from point to segment.
+func segmentDistance(x, y float64, u, v layout.Point) float64 {
+	dx, dy := v.X-u.X, v.Y-u.Y
+	t := 0.0
+	if l := dx*dx + dy*dy; l > 0 {
+		t = math.Max(0, math.Min(1, ((x-u.X)*dx+(y-u.Y)*dy)/l))
+	}
+	return math.Hypot(x-(u.X+t*dx), y-(u.Y+t*dy))
+}
+
+func floatToFixed(v float64) fixed.Int26_6 {
+	return fixed.Int26_6(math.Round(v * 64))
+}
+
+// fontFaces is cache of font faces by size.
+type fontFaces struct {
+	font  *opentype.Font
+	faces map[float64]font.Face
+}
+
+func newFontFaces(ctx context.Context) (*fontFaces, error) {
+	if goFontErr != nil {
+		return nil, fmt.Errorf("can not parse font: %w", goFontErr)
+	}
+	return &fontFaces{font: goFont, faces: map[float64]font.Face{}}, nil
+}
+
+// Face of size in pixels.
+func (s *fontFaces) Face(size float64) (font.Face, error) {
+	if face, ok := s.faces[size]; ok {
+		return face, nil
+	}
+	face, err := opentype.NewFace(s.font, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingNone})
+	if err != nil {
+		return nil, fmt.Errorf("can not make font face: %w", err)
+	}
+	s.faces[size] = face
+	return face, nil
+}
+
+func (s *fontFaces) Close() {
+	for _, face := range s.faces {
+		face.Close()
+	}
+}
@@ -3,23 +3,25 @@ package render
 import (
 	"context"
 	"image/color"
+	"math"
 
 	"github.com/nikolaydubina/go-instrument-example/treemap"
 	"github.com/nikolaydubina/go-instrument-example/treemap/layout"
 )
 
 const (
-	fontSize             int     = 12
-	textHeightMultiplier float64 = 0.8
-	textWidthMultiplier  float64 = 0.8
-	tooSmallBoxHeight    float64 = 5
-	tooSmallBoxWidth     float64 = 5
-	textMarginH          float64 = 2
+	fontSize          int     = 12
+	tooSmallBoxHeight float64 = 5
+	tooSmallBoxWidth  float64 = 5
+	textMarginH       float64 = 2
 )
 
 // UIText is spec on how to render text.
+// Text is full text, what is drawn is in Lines, which can be wrapped or truncated.
+// X, Y, W, H are bounds of all lines.
 type UIText struct {
 	Text  string
+	Lines []UITextLine
 	X     float64
 	Y     float64
 	H     float64
@@ -28,8 +30,23 @@ type UIText struct {
 	Color color.Color
 }
 
+// UITextLine is line of text that starts at X on baseline Y.
+// W is width of line after scale.
+type UITextLine struct {
+	Text string
+	X    float64
+	Y    float64
+	W    float64
+}
+
 // UIBox is spec on how to render a box. Could be Root.
+// Path, Size and Heat are of node this box is made from.
+// If Polygon is set, then box is this polygon and X, Y, W, H are its bounds.
 type UIBox struct {
+	Path        string
+	Size        float64
+	Heat        float64
+	HasHeat     bool
 	Title       *UIText
 	X           float64
 	Y           float64
@@ -40,30 +57,63 @@ type UIBox struct {
 	IsRoot      bool
 	Color       color.Color
 	BorderColor color.Color
+	BorderWidth float64
+	Polygon     layout.Polygon
 }
 
 func (f UIBox) IsEmpty() bool {
 	return f.W == 0 || f.H == 0
 }
 
+// strokeWidth is width of border, 1 if it is not set.
+func (f UIBox) strokeWidth() float64 {
+	if f.BorderWidth <= 0 {
+		return 1
+	}
+	return f.BorderWidth
+}
+
 type Colorer interface {
 	ColorBox(ctx context.Context, tree treemap.Tree, node string) color.Color
 	ColorText(ctx context.Context, tree treemap.Tree, node string) color.Color
 }
 
+// Borderer overrides border of some boxes, for example to highlight them.
+type Borderer interface {
+	BorderBox(ctx context.Context, tree treemap.Tree, node string) (c color.Color, width float64, ok bool)
+}
+
+// UITreeMapBuilder makes boxes of tree.
+// All boxes have same border, unless Borderer is set and overrides it.
+// Children are partitioned by Layout, which is Squarify if it is not set.
+// If Hints are set, children that were in previous treemap are kept close to their previous boxes and others are placed by Layout.
+// Hints are not used by PolygonLayout.
+// If PolygonLayout is set, then boxes are polygons made by it inside of Container, which is rectangle if not set.
+// Titles of leaves are wrapped onto several lines if WrapText is set, and are in center of box if CenterLeafText is set.
 type UITreeMapBuilder struct {
-	Colorer     Colorer
-	BorderColor color.Color
+	Colorer       Colorer
+	BorderColor   color.Color
+	Borderer      Borderer
+	Layout        layout.Layout
+	Hints         LayoutHints
+	PolygonLayout layout.PolygonLayout
+	Container     func(box layout.Box) layout.Polygon
+
+	WrapText       bool
+	CenterLeafText bool
 }
 
 func (s UITreeMapBuilder) NewUITreeMap(ctx context.Context, tree treemap.Tree, w, h, margin, padding, paddingRoot float64) UIBox {
-	t := UIBox{
-		X:           0 + paddingRoot,
-		Y:           0 + paddingRoot,
-		W:           w - (2 * paddingRoot),
-		H:           h - (2 * paddingRoot),
-		IsInvisible: true,
-		IsRoot:      true,
+	t := newUIRoot(w, h, paddingRoot)
+
+	if s.PolygonLayout != nil {
+		box := layout.Box{X: t.X, Y: t.Y, W: t.W, H: t.H}
+		container := layout.RectanglePolygon(box)
+		if s.Container != nil {
+			container = s.Container(box)
+		}
+		t.Children = []UIBox{s.NewUIPolygon(ctx, tree.Root, tree, container, margin, padding)}
+		return t
 	}
 
 	t.Children = []UIBox{
@@ -79,37 +129,32 @@ func (s UITreeMapBuilder) NewUIBox(ctx context.Context, node string, tree treema
 		return UIBox{}
 	}
 
-	t := UIBox{
-		X:           x + margin,
-		Y:           y + margin,
-		W:           w - (2 * margin),
-		H:           h - (2 * margin),
-		Color:       s.Colorer.ColorBox(ctx, tree, node),
-		BorderColor: s.BorderColor,
-	}
+	t := s.newBox(ctx, node, tree)
+	t.X = x + margin
+	t.Y = y + margin
+	t.W = w - (2 * margin)
+	t.H = h - (2 * margin)
+
+	isLeaf := len(tree.To[node]) == 0
 
-	var textHeight float64
-	if title := tree.Nodes[node].Name; title != "" && title != "some-secret-string" {
+	var titleHeight float64
+	if title := tree.Nodes[node].Name; title != "" && title != treemap.ArtificialRoot {
 		// fit text
 		// margin here and padding to account for children
 		w := t.W - (2 * padding) - (2 * margin)
 		h := t.H - (2 * padding) - (2 * margin) - (2 * textMarginH)
-		if scale, th := fitText(ctx, title, fontSize, w); scale > 0 && th > 0 && th < h {
-			textHeight = th
+		if !isLeaf {
+			// title of parent is single line on top of children
+			h = math.Min(h, textHeight(float64(fontSize)))
+		}
+		if lines, scale := fitLabel(ctx, title, w, h, s.WrapText && isLeaf); len(lines) > 0 {
 			// if enough space for text, then add
-			t.Title = &UIText{
-				Text:  title,
-				X:     t.X + padding + margin,
-				Y:     t.Y + padding + textMarginH,
-				W:     w,
-				H:     textHeight,
-				Scale: scale,
-				Color: s.Colorer.ColorText(ctx, tree, node),
-			}
+			t.Title = newUIText(title, lines, scale, t.X+padding+margin, t.Y+padding+textMarginH, w, h, s.CenterLeafText && isLeaf, s.Colorer.ColorText(ctx, tree, node))
+			titleHeight = t.Title.H
 		}
 	}
 
-	if len(tree.To[node]) == 0 {
+	if isLeaf {
 		return t
 	}
 
@@ -120,11 +165,25 @@ func (s UITreeMapBuilder) NewUIBox(ctx context.Context, node string, tree treema
 
 	childrenContainer := layout.Box{
 		X: t.X + padding,
-		Y: t.Y + padding + textHeight + (2 * textMarginH),
+		Y: t.Y + padding + titleHeight + (2 * textMarginH),
 		W: t.W - (2 * padding),
-		H: t.H - (2 * padding) - textHeight - (2 * textMarginH),
+		H: t.H - (2 * padding) - titleHeight - (2 * textMarginH),
+	}
+	l := s.Layout
+	if l == nil {
+		l = layout.Squarify
+	}
+	// hints are by path of box, which is not key of node after collapsing long paths
+	paths := make([]string, 0, len(tree.To[node]))
+	for _, toPath := range tree.To[node] {
+		paths = append(paths, nodePath(tree, toPath))
+	}
+	var boxes []layout.Box
+	if hints := s.Hints.childHints(paths, childrenContainer); hints != nil {
+		boxes = layout.Stable(ctx, childrenContainer, areas, hints, l)
+	} else {
+		boxes = l(ctx, childrenContainer, areas)
 	}
-	boxes := layout.Squarify(ctx, childrenContainer, areas)
 
 	for i, toPath := range tree.To[node] {
 		if boxes[i] == layout.NilBox {
@@ -150,39 +209,106 @@ func (s UITreeMapBuilder) NewUIBox(ctx context.Context, node string, tree treema
 	return t
 }
 
-func nodeSize(tree treemap.Tree, node string) float64 {
-	if n, ok := tree.Nodes[node]; ok {
-		return n.Size
+// newBox has fields of box that do not depend on geometry.
+func (s UITreeMapBuilder) newBox(ctx context.Context, node string, tree treemap.Tree) UIBox {
+	t := UIBox{
+		Path:        nodePath(tree, node),
+		Size:        nodeSize(tree, node),
+		Heat:        tree.Nodes[node].Heat,
+		HasHeat:     tree.Nodes[node].HasHeat,
+		Color:       s.Colorer.ColorBox(ctx, tree, node),
+		BorderColor: s.BorderColor,
+		BorderWidth: 1,
 	}
-	var s float64
-	for _, child := range tree.To[node] {
-		s += nodeSize(tree, child)
+	if s.Borderer != nil {
+		if c, w, ok := s.Borderer.BorderBox(ctx, tree, node); ok {
+			t.BorderColor = c
+			t.BorderWidth = w
+		}
 	}
-	return s
+	return t
 }
 
-// compute scale to fit worst dimension
-func fitText(ctx context.Context, text string, fontSize int, W float64) (scale float64, h float64) {
-	w := textWidth(text, float64(fontSize))
-	h = textHeight(text, float64(fontSize))
+// NewUIPolygon is same as NewUIBox, but for polygon made by PolygonLayout.
+// Title of leaf is in center of polygon, title of parent is on top of its children.
+func (s UITreeMapBuilder) NewUIPolygon(ctx context.Context, node string, tree treemap.Tree, polygon layout.Polygon, margin float64, padding float64) UIBox {
+	polygon = polygon.Inset(margin)
+	inner := polygon.Inset(padding)
+	bounds := polygon.Bounds()
+	if len(inner) < 3 || bounds.W < tooSmallBoxWidth || bounds.H < tooSmallBoxHeight {
+		// too small, do not render
+		return UIBox{}
+	}
+
+	t := s.newBox(ctx, node, tree)
+	t.X = bounds.X
+	t.Y = bounds.Y
+	t.W = bounds.W
+	t.H = bounds.H
+	t.Polygon = polygon
+
+	isLeaf := len(tree.To[node]) == 0
+
+	childrenContainer := inner
+	if title := tree.Nodes[node].Name; title != "" && title != treemap.ArtificialRoot {
+		th := textHeight(float64(fontSize))
+		y := inner.Bounds().Y + textMarginH
+		if isLeaf {
+			y = inner.Centroid().Y - th/2
+		}
+		// text fits between crossings of polygon on top and bottom of text
+		x0, x1, okTop := inner.Chord(y)
+		bx0, bx1, okBottom := inner.Chord(y + th)
+		x0, x1 = math.Max(x0, bx0), math.Min(x1, bx1)
+		if okTop && okBottom && x1 > x0 {
+			if lines, scale := fitLabel(ctx, title, x1-x0, th, false); len(lines) > 0 {
+				t.Title = newUIText(title, lines, scale, x0, y, x1-x0, th, true, s.Colorer.ColorText(ctx, tree, node))
+				if !isLeaf {
+					childrenContainer = inner.ClipHalfPlane(0, 1, t.Title.Y+t.Title.H+textMarginH)
+				}
+			}
+		}
+	}
 
-	scale = 1.0
-	if wscale := W / w; wscale < scale {
-		scale = wscale
+	if isLeaf || len(childrenContainer) < 3 {
+		return t
 	}
 
-	H := textHeight(text, float64(fontSize))
-	if hscale := H / h; hscale < scale {
-		scale = hscale
+	areas := make([]float64, 0, len(tree.To[node]))
+	for _, toPath := range tree.To[node] {
+		areas = append(areas, nodeSize(tree, toPath))
 	}
 
-	return scale, h
+	polygons := s.PolygonLayout(ctx, childrenContainer, areas)
+	for i, toPath := range tree.To[node] {
+		if polygons[i] == nil {
+			continue
+		}
+		box := s.NewUIPolygon(ctx, toPath, tree, polygons[i], margin, padding)
+		if box.IsEmpty() {
+			continue
+		}
+		t.Children = append(t.Children, box)
+	}
+
+	return t
 }
 
-func textWidth(text string, fontSize float64) float64 {
-	return fontSize * float64(len(text)) * textWidthMultiplier
+// nodePath is path of node, which is key of node if path is not set.
+func nodePath(tree treemap.Tree, node string) string {
+	if n, ok := tree.Nodes[node]; ok && n.Path != "" {
+		return n.Path
+	}
+	return node
 }
 
-func textHeight(text string, fontSize float64) float64 {
-	return fontSize * textHeightMultiplier
+func nodeSize(tree treemap.Tree, node string) float64 {
+	if n, ok := tree.Nodes[node]; ok {
+		return n.Size
+	}
+	var s float64
+	for _, child := range tree.To[node] {
+		s += nodeSize(tree, child)
+	}
+	return s
 }
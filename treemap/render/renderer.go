@@ -0,0 +1,90 @@
+package render
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"sort"
+	"strings"
+)
+
+// Renderer writes treemap spec to output in some format.
+type Renderer interface {
+	Render(ctx context.Context, out io.Writer, root UIBox, w, h float64) error
+}
+
+// Format is named output format.
+type Format struct {
+	Name     string
+	MIMEType string
+	Renderer Renderer
+}
+
+// Formats is registry of output formats by name and MIME type.
+type Formats struct {
+	byName map[string]Format
+	byMIME map[string]Format
+}
+
+func NewFormats() *Formats {
+	return &Formats{
+		byName: map[string]Format{},
+		byMIME: map[string]Format{},
+	}
+}
+
+// NewDefaultFormats has all formats of this package.
+func NewDefaultFormats() *Formats {
+	formats := NewFormats()
+	formats.Register(Format{Name: "svg", MIMEType: "image/svg+xml", Renderer: SVGRenderer{}})
+	formats.Register(Format{Name: "html", MIMEType: "text/html", Renderer: HTMLRenderer{}})
+	formats.Register(Format{Name: "png", MIMEType: "image/png", Renderer: PNGRenderer{}})
+	formats.Register(Format{Name: "json", MIMEType: "application/json", Renderer: JSONRenderer{}})
+	return formats
+}
+
+// Register adds format. Format with same name or MIME type is replaced.
+func (s *Formats) Register(format Format) error {
+	if format.Name == "" || format.MIMEType == "" || format.Renderer == nil {
+		return fmt.Errorf("format(%s) should have name, MIME type and renderer", format.Name)
+	}
+	s.byName[format.Name] = format
+	s.byMIME[format.MIMEType] = format
+	return nil
+}
+
+func (s *Formats) ByName(name string) (Format, bool) {
+	f, ok := s.byName[name]
+	return f, ok
+}
+
+func (s *Formats) ByMIMEType(mimeType string) (Format, bool) {
+	f, ok := s.byMIME[mimeType]
+	return f, ok
+}
+
+// Names of registered formats in sorted order.
+func (s *Formats) Names() []string {
+	names := make([]string, 0, len(s.byName))
+	for name := range s.byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Negotiate picks first registered format from value of HTTP Accept header.
+// Quality values are not taken into account.
+func (s *Formats) Negotiate(accept string) (Format, bool) {
+	for _, v := range strings.Split(accept, ",") {
+		mimeType, _, err := mime.ParseMediaType(strings.TrimSpace(v))
+		if err != nil {
+			continue
+		}
+		if f, ok := s.byMIME[mimeType]; ok {
+			return f, true
+		}
+	}
+	return Format{}, false
+}
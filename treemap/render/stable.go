@@ -0,0 +1,101 @@
+package render
+
+import (
+	"context"
+	"math"
+
+	"github.com/nikolaydubina/go-instrument-example/treemap/layout"
+)
+
+// LayoutHints are boxes of previous treemap by path.
+// They are used to keep boxes close to where they were before.
+type LayoutHints map[string]layout.Box
+
+// NewLayoutHints collects boxes of previous treemap.
+// Coordinates are relative to size of root, so that previous treemap can be of other size.
+func NewLayoutHints(root UIBox) LayoutHints {
+	hints := LayoutHints{}
+	if root.W <= 0 || root.H <= 0 {
+		return hints
+	}
+	var collect func(b UIBox)
+	collect = func(b UIBox) {
+		if b.Path != "" {
+			hints[b.Path] = layout.Box{
+				X: (b.X - root.X) / root.W,
+				Y: (b.Y - root.Y) / root.H,
+				W: b.W / root.W,
+				H: b.H / root.H,
+			}
+		}
+		for _, child := range b.Children {
+			collect(child)
+		}
+	}
+	collect(root)
+	return hints
+}
+
+// childHints are hints of children mapped into container.
+// Frame of previous children is their bounding box, as previous container itself is not known.
+// Returns nil if none of children have hints.
+func (s LayoutHints) childHints(paths []string, container layout.Box) []layout.Box {
+	var frame layout.Box
+	found := false
+	for _, path := range paths {
+		h, ok := s[path]
+		if !ok {
+			continue
+		}
+		if !found {
+			frame, found = h, true
+			continue
+		}
+		x0, y0 := math.Min(frame.X, h.X), math.Min(frame.Y, h.Y)
+		x1, y1 := math.Max(frame.X+frame.W, h.X+h.W), math.Max(frame.Y+frame.H, h.Y+h.H)
+		frame = layout.Box{X: x0, Y: y0, W: x1 - x0, H: y1 - y0}
+	}
+	if !found || frame.W <= 0 || frame.H <= 0 {
+		return nil
+	}
+
+	hints := make([]layout.Box, len(paths))
+	for i, path := range paths {
+		h, ok := s[path]
+		if !ok {
+			continue
+		}
+		hints[i] = layout.Box{
+			X: container.X + (h.X-frame.X)/frame.W*container.W,
+			Y: container.Y + (h.Y-frame.Y)/frame.H*container.H,
+			W: h.W / frame.W * container.W,
+			H: h.H / frame.H * container.H,
+		}
+	}
+	return hints
+}
+
+// LayoutDrift is how much boxes moved between previous and current treemap.
+// It is average over paths in both treemaps of distance between corners of box,
+// in coordinates relative to size of root, so 0 is same layout and 1 is box moved by size of treemap.
+// Paths that were added or removed are not counted.
+func LayoutDrift(ctx context.Context, prev UIBox, curr UIBox) float64 {
+	prevHints, currHints := NewLayoutHints(prev), NewLayoutHints(curr)
+
+	var total float64
+	var n int
+	for path, a := range prevHints {
+		b, ok := currHints[path]
+		if !ok {
+			continue
+		}
+		dx0, dy0 := b.X-a.X, b.Y-a.Y
+		dx1, dy1 := (b.X+b.W)-(a.X+a.W), (b.Y+b.H)-(a.Y+a.H)
+		total += (math.Sqrt(dx0*dx0+dy0*dy0) + math.Sqrt(dx1*dx1+dy1*dy1)) / 2
+		n++
+	}
+	if n == 0 {
+		return 0
+	}
+	return total / float64(n)
+}
@@ -0,0 +1,73 @@
+package render_test
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/nikolaydubina/go-instrument-example/treemap"
+	"github.com/nikolaydubina/go-instrument-example/treemap/layout"
+	"github.com/nikolaydubina/go-instrument-example/treemap/render"
+)
+
+func findBox(b render.UIBox, path string) (render.UIBox, bool) {
+	if b.Path == path {
+		return b, true
+	}
+	for _, child := range b.Children {
+		if v, ok := findBox(child, path); ok {
+			return v, true
+		}
+	}
+	return render.UIBox{}, false
+}
+
+func TestLayoutHintsOfCollapsedPath(t *testing.T) {
+	ctx := context.Background()
+
+	tree := treemap.Tree{
+		Nodes: map[string]treemap.Node{
+			"r":       {Path: "r"},
+			"r/p":     {Path: "r/p"},
+			"r/p/q":   {Path: "r/p/q"},
+			"r/p/q/x": {Path: "r/p/q/x", Size: 3},
+			"r/p/q/y": {Path: "r/p/q/y", Size: 1},
+			"r/z":     {Path: "r/z", Size: 4},
+		},
+		To: map[string][]string{
+			"r":     {"r/p", "r/z"},
+			"r/p":   {"r/p/q"},
+			"r/p/q": {"r/p/q/x", "r/p/q/y"},
+		},
+		Root: "r",
+	}
+	treemap.SumSizeImputer{EmptyLeafSize: 1}.ImputeSize(ctx, tree)
+	treemap.SetNamesFromPaths(ctx, &tree)
+	treemap.CollapseLongPaths(ctx, &tree)
+
+	if n := tree.Nodes["r/p"]; n.Path != "r/p/q" {
+		t.Fatalf("expected collapsed node to have path of last child, got %s", n.Path)
+	}
+
+	prev := render.UITreeMapBuilder{
+		Colorer: render.NoneColorer{},
+		Layout:  layout.SliceAndDice,
+	}.NewUITreeMap(ctx, tree, 600, 400, 4, 4, 16)
+
+	curr := render.UITreeMapBuilder{
+		Colorer: render.NoneColorer{},
+		Layout:  layout.Squarify,
+		Hints:   render.NewLayoutHints(prev),
+	}.NewUITreeMap(ctx, tree, 600, 400, 4, 4, 16)
+
+	for _, path := range []string{"r/p/q", "r/z"} {
+		a, okA := findBox(prev, path)
+		b, okB := findBox(curr, path)
+		if !okA || !okB {
+			t.Fatalf("box(%s) not found", path)
+		}
+		if math.Abs(a.X-b.X) > 1e-6 || math.Abs(a.Y-b.Y) > 1e-6 || math.Abs(a.W-b.W) > 1e-6 || math.Abs(a.H-b.H) > 1e-6 {
+			t.Errorf("box(%s) moved from %v,%v,%v,%v to %v,%v,%v,%v", path, a.X, a.Y, a.W, a.H, b.X, b.Y, b.W, b.H)
+		}
+	}
+}
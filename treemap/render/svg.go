@@ -1,106 +1,166 @@
 package render
 
 import (
+	"bufio"
 	"context"
+	"encoding/xml"
+	"errors"
 	"fmt"
 	"image/color"
+	"io"
+	"strings"
+
+	"github.com/nikolaydubina/go-instrument-example/treemap/layout"
 )
 
+// SVGRenderer streams boxes as SVG elements.
+// All text and attributes are escaped by XML encoder.
+// Each box has title with its path, which is shown as tooltip and used by screen readers.
 type SVGRenderer struct{}
 
-func (r SVGRenderer) Render(ctx context.Context, root UIBox, w, h float64) []byte {
+func (r SVGRenderer) Render(ctx context.Context, out io.Writer, root UIBox, w, h float64) error {
 	if !root.IsRoot {
-		return nil
+		return errors.New("box is not root")
 	}
 
-	s := fmt.Sprintf(`
-<svg 
-	xmlns="http://www.w3.org/2000/svg" 
-	xmlns:xlink="http://www.w3.org/1999/xlink" 
-	viewBox="0 0 %f %f" 
-	style="%s"
->`,
-		w,
-		h,
-		"background: white none repeat scroll 0% 0%;",
-	)
+	b := bufio.NewWriter(out)
+	enc := xml.NewEncoder(b)
+	enc.Indent("", "\t")
+
+	svg := xml.StartElement{
+		Name: xml.Name{Local: "svg"},
+		Attr: []xml.Attr{
+			{Name: xml.Name{Local: "xmlns"}, Value: "http://www.w3.org/2000/svg"},
+			{Name: xml.Name{Local: "viewBox"}, Value: fmt.Sprintf("0 0 %f %f", w, h)},
+			{Name: xml.Name{Local: "style"}, Value: "background: white none repeat scroll 0% 0%;"},
+			{Name: xml.Name{Local: "role"}, Value: "img"},
+		},
+	}
+	if err := enc.EncodeToken(svg); err != nil {
+		return err
+	}
+	if err := encodeElement(enc, "title", nil, "treemap"); err != nil {
+		return err
+	}
 
 	var q UIBox
 	que := []UIBox{root}
 	for len(que) > 0 {
 		q, que = que[0], que[1:]
 		que = append(que, q.Children...)
-		s += BoxSVG(ctx, q) + "\n"
+		if err := BoxSVG(ctx, enc, q); err != nil {
+			return err
+		}
 	}
 
-	s += `</svg>`
-
-	return []byte(s)
+	if err := enc.EncodeToken(svg.End()); err != nil {
+		return err
+	}
+	if err := enc.Flush(); err != nil {
+		return err
+	}
+	return b.Flush()
 }
 
-func BoxSVG(ctx context.Context, q UIBox) string {
+func BoxSVG(ctx context.Context, enc *xml.Encoder, q UIBox) error {
 	if q.IsInvisible {
-		return ""
-	}
-	r, g, b, a := color.White.RGBA()
-	if q.Color != color.Opaque {
-		r, g, b, a = q.Color.RGBA()
-	}
-
-	r = r >> 8
-	g = g >> 8
-	b = b >> 8
-
-	br, bg, bb, ba := color.White.RGBA()
-	if q.BorderColor != color.Opaque {
-		br, bg, bb, ba = q.BorderColor.RGBA()
-	}
-	br = br >> 8
-	bg = bg >> 8
-	bb = bb >> 8
-
-	return fmt.Sprintf(`
-<g>
-	<rect x="%f" y="%f" width="%f" height="%f" style="%s" />
-	%s
-</g>
-`,
-		q.X,
-		q.Y,
-		q.W,
-		q.H,
-		fmt.Sprintf("fill: rgba(%d, %d, %d, %d);opacity:1;fill-opacity:1;stroke:rgba(%d,%d,%d,%d);stroke-width:1px;stroke-opacity:1;", r, g, b, a, br, bg, bb, ba),
-		TextSVG(ctx, q.Title),
-	)
+		return nil
+	}
+
+	g := xml.StartElement{Name: xml.Name{Local: "g"}}
+	if err := enc.EncodeToken(g); err != nil {
+		return err
+	}
+
+	if q.Path != "" {
+		if err := encodeElement(enc, "title", nil, q.Path); err != nil {
+			return err
+		}
+	}
+
+	style := xml.Attr{Name: xml.Name{Local: "style"}, Value: fmt.Sprintf("fill: %s;opacity:1;fill-opacity:1;stroke:%s;stroke-width:%gpx;stroke-opacity:1;", cssColor(q.Color, color.White), cssColor(q.BorderColor, color.White), q.strokeWidth())}
+	if len(q.Polygon) > 0 {
+		polygon := []xml.Attr{
+			{Name: xml.Name{Local: "points"}, Value: svgPoints(q.Polygon)},
+			style,
+		}
+		if err := encodeElement(enc, "polygon", polygon, ""); err != nil {
+			return err
+		}
+	} else {
+		rect := []xml.Attr{
+			{Name: xml.Name{Local: "x"}, Value: fmt.Sprintf("%f", q.X)},
+			{Name: xml.Name{Local: "y"}, Value: fmt.Sprintf("%f", q.Y)},
+			{Name: xml.Name{Local: "width"}, Value: fmt.Sprintf("%f", q.W)},
+			{Name: xml.Name{Local: "height"}, Value: fmt.Sprintf("%f", q.H)},
+			style,
+		}
+		if err := encodeElement(enc, "rect", rect, ""); err != nil {
+			return err
+		}
+	}
+
+	if err := TextSVG(ctx, enc, q.Title); err != nil {
+		return err
+	}
+
+	return enc.EncodeToken(g.End())
 }
 
-func TextSVG(ctx context.Context, t *UIText) string {
+func TextSVG(ctx context.Context, enc *xml.Encoder, t *UIText) error {
 	if t == nil {
-		return ""
-	}
-
-	r, g, b, a := color.Black.RGBA()
-	if t.Color != color.Opaque {
-		r, g, b, a = t.Color.RGBA()
-	}
-
-	r = r >> 8
-	g = g >> 8
-	b = b >> 8
-
-	s := fmt.Sprintf(`
-<text 
-	data-notex="1" 
-	text-anchor="start"
-	transform="translate(%f,%f) scale(%f)"
-	style="%s" 
-	data-math="N">%s</text>
-		`,
-		t.X,
-		t.Y+t.H,
-		t.Scale,
-		fmt.Sprintf("font-family: Open Sans, verdana, arial, sans-serif !important; font-size: %dpx; fill: rgb(%d, %d, %d, %d); fill-opacity: 1; white-space: pre;", fontSize, r, g, b, a),
-		t.Text,
-	)
-	return s
+		return nil
+	}
+
+	// text is measured with Go font, length is set so that other fonts do not overflow box
+	for _, line := range t.lines() {
+		attr := []xml.Attr{
+			{Name: xml.Name{Local: "data-notex"}, Value: "1"},
+			{Name: xml.Name{Local: "text-anchor"}, Value: "start"},
+			{Name: xml.Name{Local: "transform"}, Value: fmt.Sprintf("translate(%f,%f) scale(%f)", line.X, line.Y, t.Scale)},
+			{Name: xml.Name{Local: "textLength"}, Value: fmt.Sprintf("%f", line.W/t.Scale)},
+			{Name: xml.Name{Local: "lengthAdjust"}, Value: "spacingAndGlyphs"},
+			{Name: xml.Name{Local: "style"}, Value: fmt.Sprintf("font-family: Go, Open Sans, verdana, arial, sans-serif !important; font-size: %dpx; fill: %s; fill-opacity: 1; white-space: pre;", fontSize, cssColor(t.Color, color.Black))},
+			{Name: xml.Name{Local: "data-math"}, Value: "N"},
+		}
+		if err := encodeElement(enc, "text", attr, line.Text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// svgPoints formats points of polygon as value of points attribute.
+func svgPoints(p layout.Polygon) string {
+	var b strings.Builder
+	for i, v := range p {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		fmt.Fprintf(&b, "%f,%f", v.X, v.Y)
+	}
+	return b.String()
+}
+
+// encodeElement writes element with escaped text content.
+func encodeElement(enc *xml.Encoder, name string, attr []xml.Attr, text string) error {
+	start := xml.StartElement{Name: xml.Name{Local: name}, Attr: attr}
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+	if text != "" {
+		if err := enc.EncodeToken(xml.CharData(text)); err != nil {
+			return err
+		}
+	}
+	return enc.EncodeToken(start.End())
+}
+
+// cssColor formats color as CSS rgba, if color is not set then default is used.
+func cssColor(c color.Color, d color.Color) string {
+	if c == nil {
+		c = d
+	}
+	r, g, b, a := c.RGBA()
+	return fmt.Sprintf("rgba(%d, %d, %d, %.3f)", r>>8, g>>8, b>>8, float64(a)/0xffff)
 }
@@ -0,0 +1,93 @@
+package render_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/tools/cover"
+
+	"github.com/nikolaydubina/go-instrument-example/go-cover-treemap/covertreemap"
+	"github.com/nikolaydubina/go-instrument-example/treemap"
+	"github.com/nikolaydubina/go-instrument-example/treemap/render"
+)
+
+func TestSVGRendererEscapesPath(t *testing.T) {
+	ctx := context.Background()
+
+	root := render.UIBox{
+		W:           100,
+		H:           100,
+		IsInvisible: true,
+		IsRoot:      true,
+		Children: []render.UIBox{
+			{
+				Path:  "a/<b>&c",
+				W:     100,
+				H:     100,
+				Title: &render.UIText{Text: "<b>&c", W: 50, H: 10, Scale: 1},
+			},
+		},
+	}
+
+	var b bytes.Buffer
+	if err := (render.SVGRenderer{}).Render(ctx, &b, root, 100, 100); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+
+	if strings.Contains(out, "<b>") || strings.Contains(out, "&c") {
+		t.Errorf("path is not escaped: %s", out)
+	}
+	for _, s := range []string{"<title>a/&lt;b&gt;&amp;c</title>", "&lt;b&gt;&amp;c</text>"} {
+		if !strings.Contains(out, s) {
+			t.Errorf("expected %s in: %s", s, out)
+		}
+	}
+}
+
+// BenchmarkSVGRenderer renders parts of hugo coverage, time per box should not grow with size of tree.
+func BenchmarkSVGRenderer(b *testing.B) {
+	ctx := context.Background()
+
+	profiles, err := cover.ParseProfiles("../../go-cover-treemap/testdata/hugo.cover")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for _, n := range []int{len(profiles) / 8, len(profiles) / 4, len(profiles) / 2, len(profiles)} {
+		tree, err := covertreemap.NewCoverageTreemapBuilder(true).CoverageTreemapFromProfiles(ctx, profiles[:n])
+		if err != nil {
+			b.Fatal(err)
+		}
+		treemap.SumSizeImputer{EmptyLeafSize: 1}.ImputeSize(ctx, *tree)
+		treemap.SetNamesFromPaths(ctx, tree)
+		treemap.CollapseLongPaths(ctx, tree)
+
+		const w, h = 4096, 4096
+		spec := render.UITreeMapBuilder{Colorer: render.NoneColorer{}}.NewUITreeMap(ctx, *tree, w, h, 4, 4, 16)
+		boxes := countBoxes(spec)
+
+		b.Run(fmt.Sprintf("files=%d/boxes=%d", n, boxes), func(b *testing.B) {
+			start := time.Now()
+			for i := 0; i < b.N; i++ {
+				if err := (render.SVGRenderer{}).Render(ctx, io.Discard, spec, w, h); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(time.Since(start).Nanoseconds())/float64(b.N*boxes), "ns/box")
+		})
+	}
+}
+
+func countBoxes(box render.UIBox) int {
+	n := 1
+	for _, child := range box.Children {
+		n += countBoxes(child)
+	}
+	return n
+}
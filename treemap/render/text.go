@@ -0,0 +1,229 @@
+package render
+
+import (
+	"context"
+	"image/color"
+	"math"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	// minTextScale is smallest scale of readable text, longer text is wrapped or truncated.
+	minTextScale float64 = 0.6
+	ellipsis     string  = "…"
+)
+
+// goFont is bundled Go font, text is measured by its metrics.
+// Fonts are safe for concurrent use with separate buffers.
+var goFont, goFontErr = sfnt.Parse(goregular.TTF)
+
+// textWidth is width of text in pixels when drawn with font of size in pixels.
+// Unknown glyphs are measured as missing glyph.
+func textWidth(text string, fontSize float64) float64 {
+	if goFontErr != nil {
+		return fontSize * float64(utf8.RuneCountInString(text)) * 0.6
+	}
+
+	var b sfnt.Buffer
+	ppem := fixed.Int26_6(math.Round(fontSize * 64))
+
+	var w fixed.Int26_6
+	var prev sfnt.GlyphIndex
+	for i, r := range []rune(text) {
+		idx, err := goFont.GlyphIndex(&b, r)
+		if err != nil {
+			continue
+		}
+		if i > 0 {
+			if k, err := goFont.Kern(&b, prev, idx, ppem, font.HintingNone); err == nil {
+				w += k
+			}
+		}
+		if a, err := goFont.GlyphAdvance(&b, idx, ppem, font.HintingNone); err == nil {
+			w += a
+		}
+		prev = idx
+	}
+	return float64(w) / 64
+}
+
+// textHeight is height of line of text, from top of ascent to bottom of descent.
+func textHeight(fontSize float64) float64 {
+	ascent, descent := textMetrics(fontSize)
+	return ascent + descent
+}
+
+// textMetrics are distances from baseline to top and bottom of line.
+func textMetrics(fontSize float64) (ascent, descent float64) {
+	if goFontErr != nil {
+		return fontSize * 0.8, fontSize * 0.2
+	}
+	var b sfnt.Buffer
+	m, err := goFont.Metrics(&b, fixed.Int26_6(math.Round(fontSize*64)), font.HintingNone)
+	if err != nil {
+		return fontSize * 0.8, fontSize * 0.2
+	}
+	return float64(m.Ascent) / 64, float64(m.Descent) / 64
+}
+
+// fitLabel fits text into area of w x h.
+// Text is made smaller to fit, but not smaller than minTextScale.
+// If it still does not fit, then it is wrapped onto several lines if wrap is set, and otherwise truncated with ellipsis.
+// Returns no lines if text does not fit at all.
+func fitLabel(ctx context.Context, text string, w, h float64, wrap bool) (lines []string, scale float64) {
+	tw, th := textWidth(text, float64(fontSize)), textHeight(float64(fontSize))
+	if text == "" || tw <= 0 || w <= 0 || h <= 0 {
+		return nil, 0
+	}
+
+	if scale = math.Min(1, math.Min(w/tw, h/th)); scale >= minTextScale {
+		return []string{text}, scale
+	}
+
+	if wrap {
+		for _, scale := range []float64{1, 0.8, minTextScale} {
+			maxLines := int(h / (th * scale))
+			if maxLines < 2 {
+				continue
+			}
+			if lines := wrapText(text, w/scale, float64(fontSize)); len(lines) <= maxLines {
+				return lines, scale
+			}
+		}
+	}
+
+	if h/th < minTextScale {
+		return nil, 0
+	}
+	if line := truncateText(text, w/minTextScale, float64(fontSize)); line != "" {
+		return []string{line}, minTextScale
+	}
+	return nil, 0
+}
+
+// wrapText breaks text onto lines not wider than w.
+// Lines are broken after separators of paths and names, and anywhere if there are none.
+func wrapText(text string, w float64, fontSize float64) []string {
+	var lines []string
+	var line string
+	for _, token := range splitAfterSeparators(text) {
+		if textWidth(line+token, fontSize) <= w {
+			line += token
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+			line = ""
+		}
+		// token is too long by itself
+		for _, r := range token {
+			if line != "" && textWidth(line+string(r), fontSize) > w {
+				lines = append(lines, line)
+				line = ""
+			}
+			line += string(r)
+		}
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func splitAfterSeparators(text string) []string {
+	var tokens []string
+	start := 0
+	for i, r := range text {
+		if strings.ContainsRune("/_.- ", r) {
+			tokens = append(tokens, text[start:i+utf8.RuneLen(r)])
+			start = i + utf8.RuneLen(r)
+		}
+	}
+	if start < len(text) {
+		tokens = append(tokens, text[start:])
+	}
+	return tokens
+}
+
+// truncateText shortens text with ellipsis so that it is not wider than w.
+// Paths are shortened in the middle, so that both root and file are seen, other text is shortened at the end.
+// Returns empty string if not even one letter with ellipsis fits.
+func truncateText(text string, w float64, fontSize float64) string {
+	if textWidth(text, fontSize) <= w {
+		return text
+	}
+	runes := []rune(text)
+	middle := strings.Contains(text, "/")
+
+	cut := func(k int) string {
+		if !middle {
+			return string(runes[:k]) + ellipsis
+		}
+		head := k / 2
+		return string(runes[:head]) + ellipsis + string(runes[len(runes)-(k-head):])
+	}
+
+	// longest cut that fits
+	lo, hi := -1, len(runes)-1
+	for lo < hi {
+		k := (lo + hi + 1) / 2
+		if textWidth(cut(k), fontSize) <= w {
+			lo = k
+		} else {
+			hi = k - 1
+		}
+	}
+	if lo < 1 {
+		return ""
+	}
+	return cut(lo)
+}
+
+// newUIText places lines of text in area starting at x, y of width w and height h.
+// Lines are on top of area and start on left, or are in center of area if centered.
+func newUIText(text string, lines []string, scale float64, x, y, w, h float64, centered bool, c color.Color) *UIText {
+	ascent, descent := textMetrics(float64(fontSize))
+	lineH := (ascent + descent) * scale
+
+	t := UIText{
+		Text:  text,
+		X:     x,
+		Y:     y,
+		H:     lineH * float64(len(lines)),
+		Scale: scale,
+		Color: c,
+	}
+	if centered {
+		t.Y = y + (h-t.H)/2
+	}
+
+	for i, line := range lines {
+		lw := textWidth(line, float64(fontSize)) * scale
+		t.W = math.Max(t.W, lw)
+
+		l := UITextLine{Text: line, X: x, Y: t.Y + float64(i)*lineH + ascent*scale, W: lw}
+		if centered {
+			l.X = x + (w-lw)/2
+		}
+		t.Lines = append(t.Lines, l)
+	}
+	if centered {
+		t.X = x + (w-t.W)/2
+	}
+
+	return &t
+}
+
+// lines to draw, if there are none then text is single line with baseline at bottom of bounds.
+func (t UIText) lines() []UITextLine {
+	if len(t.Lines) > 0 || t.Text == "" {
+		return t.Lines
+	}
+	return []UITextLine{{Text: t.Text, X: t.X, Y: t.Y + t.H, W: t.W}}
+}
@@ -0,0 +1,250 @@
+package render
+
+import (
+	"context"
+	"math"
+
+	"github.com/nikolaydubina/go-instrument-example/treemap"
+	"github.com/nikolaydubina/go-instrument-example/treemap/layout"
+)
+
+// circlePoints is number of points in polygons of circles.
+const circlePoints = 64
+
+// NewUICircles makes nested circles, children are packed inside of their parent.
+// Title of leaf is in center of circle, title of parent is on top of its children.
+func (s UITreeMapBuilder) NewUICircles(ctx context.Context, tree treemap.Tree, w, h, margin, padding, paddingRoot float64) UIBox {
+	t := newUIRoot(w, h, paddingRoot)
+
+	c := layout.Circle{X: t.X + t.W/2, Y: t.Y + t.H/2, R: math.Min(t.W, t.H) / 2}
+	t.Children = []UIBox{s.newUICircle(ctx, tree.Root, tree, c, margin, padding)}
+
+	return t
+}
+
+func (s UITreeMapBuilder) newUICircle(ctx context.Context, node string, tree treemap.Tree, c layout.Circle, margin, padding float64) UIBox {
+	c.R -= margin
+	if (2*c.R) < tooSmallBoxWidth || c.R <= padding {
+		// too small, do not render
+		return UIBox{}
+	}
+
+	t := s.newBox(ctx, node, tree)
+	t.Polygon = c.Polygon(circlePoints)
+	t.X, t.Y, t.W, t.H = c.X-c.R, c.Y-c.R, 2*c.R, 2*c.R
+
+	if len(tree.To[node]) == 0 {
+		t.Title = s.fitTitle(ctx, tree, node, t.Polygon, layout.Point{X: c.X, Y: c.Y}, false)
+		return t
+	}
+
+	// children are in circle under title that touches parent from inside
+	inner := layout.Circle{X: c.X, Y: c.Y, R: c.R - padding}
+	t.Title = s.fitTitle(ctx, tree, node, t.Polygon, layout.Point{X: c.X, Y: c.Y - c.R + padding + textMarginH}, true)
+	if t.Title != nil {
+		shift := (t.Title.Y + t.Title.H + textMarginH - (c.Y - inner.R)) / 2
+		inner.Y += shift
+		inner.R -= shift
+	}
+	if inner.R <= 0 {
+		return t
+	}
+
+	areas := make([]float64, 0, len(tree.To[node]))
+	for _, toPath := range tree.To[node] {
+		areas = append(areas, nodeSize(tree, toPath))
+	}
+
+	circles := layout.PackCircles(ctx, inner, areas)
+	for i, toPath := range tree.To[node] {
+		if circles[i].R == 0 {
+			continue
+		}
+		box := s.newUICircle(ctx, toPath, tree, circles[i], margin, padding)
+		if box.IsEmpty() {
+			continue
+		}
+		t.Children = append(t.Children, box)
+	}
+
+	return t
+}
+
+// NewUIIcicle makes rows of boxes by depth, children are under their parent and split its width by size.
+func (s UITreeMapBuilder) NewUIIcicle(ctx context.Context, tree treemap.Tree, w, h, margin, paddingRoot float64) UIBox {
+	t := newUIRoot(w, h, paddingRoot)
+
+	rowH := t.H / float64(treeDepth(tree, tree.Root))
+	t.Children = []UIBox{s.newUIIcicleBox(ctx, tree.Root, tree, t.X, t.Y, t.W, rowH, margin)}
+
+	return t
+}
+
+func (s UITreeMapBuilder) newUIIcicleBox(ctx context.Context, node string, tree treemap.Tree, x, y, w, rowH, margin float64) UIBox {
+	if w < tooSmallBoxWidth || rowH < tooSmallBoxHeight {
+		// too small, do not render
+		return UIBox{}
+	}
+
+	t := s.newBox(ctx, node, tree)
+	t.X = x + margin
+	t.Y = y + margin
+	t.W = w - (2 * margin)
+	t.H = rowH - (2 * margin)
+
+	if title := tree.Nodes[node].Name; title != "" && title != treemap.ArtificialRoot {
+		isLeaf := len(tree.To[node]) == 0
+		w := t.W - (2 * textMarginH)
+		if lines, scale := fitLabel(ctx, title, w, t.H, s.WrapText && isLeaf); len(lines) > 0 {
+			t.Title = newUIText(title, lines, scale, t.X+textMarginH, t.Y, w, t.H, s.CenterLeafText && isLeaf, s.Colorer.ColorText(ctx, tree, node))
+		}
+	}
+
+	var total float64
+	for _, toPath := range tree.To[node] {
+		total += nodeSize(tree, toPath)
+	}
+	if total <= 0 {
+		return t
+	}
+
+	offset := x
+	for _, toPath := range tree.To[node] {
+		cw := w * nodeSize(tree, toPath) / total
+		box := s.newUIIcicleBox(ctx, toPath, tree, offset, y+rowH, cw, rowH, margin)
+		offset += cw
+		if box.IsEmpty() {
+			continue
+		}
+		t.Children = append(t.Children, box)
+	}
+
+	return t
+}
+
+// NewUISunburst makes rings by depth around root in center, children are in ring next to their parent and split its angle by size.
+// Angles start at the top and go clockwise.
+func (s UITreeMapBuilder) NewUISunburst(ctx context.Context, tree treemap.Tree, w, h, paddingRoot float64) UIBox {
+	t := newUIRoot(w, h, paddingRoot)
+
+	ring := math.Min(t.W, t.H) / 2 / float64(treeDepth(tree, tree.Root))
+	cx, cy := t.X+t.W/2, t.Y+t.H/2
+	t.Children = []UIBox{s.newUISector(ctx, tree.Root, tree, cx, cy, 0, ring, -math.Pi/2, 3*math.Pi/2, ring)}
+
+	return t
+}
+
+func (s UITreeMapBuilder) newUISector(ctx context.Context, node string, tree treemap.Tree, cx, cy, r0, r1, a0, a1, ring float64) UIBox {
+	if ring < tooSmallBoxHeight || (a1-a0)*(r0+r1)/2 < 1 {
+		// too small, do not render
+		return UIBox{}
+	}
+
+	t := s.newBox(ctx, node, tree)
+	center := layout.Point{X: cx, Y: cy}
+	if r0 == 0 {
+		t.Polygon = layout.CirclePolygon(layout.Box{X: cx - r1, Y: cy - r1, W: 2 * r1, H: 2 * r1}, circlePoints)
+	} else {
+		t.Polygon = layout.AnnularSector(cx, cy, r0, r1, a0, a1)
+		a := (a0 + a1) / 2
+		center = layout.Point{X: cx + (r0+r1)/2*math.Cos(a), Y: cy + (r0+r1)/2*math.Sin(a)}
+	}
+	bounds := t.Polygon.Bounds()
+	t.X, t.Y, t.W, t.H = bounds.X, bounds.Y, bounds.W, bounds.H
+	t.Title = s.fitTitle(ctx, tree, node, t.Polygon, center, false)
+
+	var total float64
+	for _, toPath := range tree.To[node] {
+		total += nodeSize(tree, toPath)
+	}
+	if total <= 0 {
+		return t
+	}
+
+	offset := a0
+	for _, toPath := range tree.To[node] {
+		da := (a1 - a0) * nodeSize(tree, toPath) / total
+		box := s.newUISector(ctx, toPath, tree, cx, cy, r1, r1+ring, offset, offset+da, ring)
+		offset += da
+		if box.IsEmpty() {
+			continue
+		}
+		t.Children = append(t.Children, box)
+	}
+
+	return t
+}
+
+// fitTitle places title of node centered at point, or with top at point, so that it is inside of polygon.
+// Text is made smaller if it does not fit, and truncated if it does not fit when smallest, nil if it does not fit at all.
+func (s UITreeMapBuilder) fitTitle(ctx context.Context, tree treemap.Tree, node string, p layout.Polygon, at layout.Point, top bool) *UIText {
+	title := tree.Nodes[node].Name
+	if title == "" || title == treemap.ArtificialRoot {
+		return nil
+	}
+
+	th := textHeight(float64(fontSize))
+	fits := func(tw, th float64) (x, y float64, ok bool) {
+		x, y = at.X-tw/2, at.Y-th/2
+		if top {
+			y = at.Y
+		}
+		corners := []layout.Point{{X: x, Y: y}, {X: x + tw, Y: y}, {X: x, Y: y + th}, {X: x + tw, Y: y + th}}
+		for _, v := range corners {
+			if !p.Contains(v) {
+				return x, y, false
+			}
+		}
+		return x, y, true
+	}
+
+	for _, scale := range []float64{1, 0.8, minTextScale} {
+		tw := textWidth(title, float64(fontSize)) * scale
+		if x, y, ok := fits(tw, th*scale); ok {
+			return newUIText(title, []string{title}, scale, x, y, tw, th*scale, false, s.Colorer.ColorText(ctx, tree, node))
+		}
+	}
+
+	// widest text that fits, then text is truncated to it
+	lo, hi := 0.0, textWidth(title, float64(fontSize))*minTextScale
+	for i := 0; i < 20; i++ {
+		if _, _, ok := fits((lo+hi)/2, th*minTextScale); ok {
+			lo = (lo + hi) / 2
+		} else {
+			hi = (lo + hi) / 2
+		}
+	}
+	line := truncateText(title, lo/minTextScale, float64(fontSize))
+	if line == "" {
+		return nil
+	}
+	tw := textWidth(line, float64(fontSize)) * minTextScale
+	x, y, ok := fits(tw, th*minTextScale)
+	if !ok {
+		return nil
+	}
+	return newUIText(title, []string{line}, minTextScale, x, y, tw, th*minTextScale, false, s.Colorer.ColorText(ctx, tree, node))
+}
+
+// newUIRoot is invisible box of whole image.
+func newUIRoot(w, h, paddingRoot float64) UIBox {
+	return UIBox{
+		X:           0 + paddingRoot,
+		Y:           0 + paddingRoot,
+		W:           w - (2 * paddingRoot),
+		H:           h - (2 * paddingRoot),
+		IsInvisible: true,
+		IsRoot:      true,
+	}
+}
+
+// treeDepth is number of levels in subtree of node.
+func treeDepth(tree treemap.Tree, node string) int {
+	d := 0
+	for _, child := range tree.To[node] {
+		if v := treeDepth(tree, child); v > d {
+			d = v
+		}
+	}
+	return d + 1
+}
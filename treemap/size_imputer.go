@@ -27,15 +27,18 @@ func (s SumSizeImputer) ImputeSizeNode(ctx context.Context, t Tree, node string)
 			v = sum
 		}
 
-		var name string
-		if parts := strings.Split(node, "/"); len(parts) > 0 {
+		name := n.Name
+		if parts := strings.Split(node, "/"); name == "" && len(parts) > 0 {
 			name = parts[len(parts)-1]
 		}
 
 		t.Nodes[node] = Node{
-			Path: node,
-			Name: name,
-			Size: v,
+			Path:       node,
+			Name:       name,
+			Size:       v,
+			Heat:       n.Heat,
+			HasHeat:    n.HasHeat,
+			Attributes: n.Attributes,
 		}
 	}
 }
@@ -0,0 +1,325 @@
+package treemap
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Transform changes tree in place.
+type Transform interface {
+	Transform(ctx context.Context, tree *Tree) error
+}
+
+// TransformFunc is function that is Transform.
+type TransformFunc func(ctx context.Context, tree *Tree) error
+
+func (f TransformFunc) Transform(ctx context.Context, tree *Tree) error { return f(ctx, tree) }
+
+// PipelineStep is named transform.
+type PipelineStep struct {
+	Name      string
+	Transform Transform
+}
+
+// Pipeline applies transforms in order and stops at first error.
+type Pipeline []PipelineStep
+
+func (s Pipeline) Transform(ctx context.Context, tree *Tree) error {
+	if tree == nil {
+		return errors.New("no tree")
+	}
+	for i, step := range s {
+		if err := step.Transform.Transform(ctx, tree); err != nil {
+			return fmt.Errorf("step(%d) %s: %w", i, step.Name, err)
+		}
+	}
+	return nil
+}
+
+// TransformSpec is name of transform and its arguments.
+type TransformSpec struct {
+	Name string            `json:"name"`
+	Args map[string]string `json:"args,omitempty"`
+}
+
+// PipelineSpec is declarative description of Pipeline.
+type PipelineSpec []TransformSpec
+
+// ParsePipelineSpec parses either JSON array of transforms or short form.
+// Short form is transforms separated by comma, each with arguments separated by colon,
+// for example `sum-size:empty=1,set-names,weighted-heat:empty=0.5`.
+// Colon in value is kept if text after it has no `=`, like in `filter:exclude=re:_test\.go$`.
+// Comma inside of brackets, braces or parentheses is kept, like in `filter:include=re:a{1,3}`, other commas need JSON form.
+func ParsePipelineSpec(s string) (PipelineSpec, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+
+	if strings.HasPrefix(s, "[") {
+		var spec PipelineSpec
+		if err := json.Unmarshal([]byte(s), &spec); err != nil {
+			return nil, fmt.Errorf("can not parse JSON: %w", err)
+		}
+		return spec, nil
+	}
+
+	steps, err := splitPipelineSteps(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var spec PipelineSpec
+	for _, v := range steps {
+		parts := strings.Split(strings.TrimSpace(v), ":")
+		t := TransformSpec{Name: parts[0]}
+		var last string
+		for _, arg := range parts[1:] {
+			key, value, ok := strings.Cut(arg, "=")
+			if !ok {
+				if last == "" {
+					return nil, fmt.Errorf("argument(%s) of transform(%s) is not key=value", arg, t.Name)
+				}
+				t.Args[last] += ":" + arg
+				continue
+			}
+			if t.Args == nil {
+				t.Args = map[string]string{}
+			}
+			t.Args[key] = value
+			last = key
+		}
+		spec = append(spec, t)
+	}
+	return spec, nil
+}
+
+// splitPipelineSteps splits short form on commas that are not inside of brackets, braces or parentheses.
+// Escaped characters, like `\{`, and brackets inside of character class, like `[(]`, are not counted.
+func splitPipelineSteps(s string) ([]string, error) {
+	var steps []string
+	depth, start, inClass := 0, 0, false
+	for i := 0; i < len(s) && depth >= 0; i++ {
+		switch c := s[i]; {
+		case c == '\\':
+			i++
+		case inClass:
+			inClass = c != ']'
+		case c == '[':
+			inClass = true
+		case c == '(' || c == '{':
+			depth++
+		case c == ')' || c == '}':
+			depth--
+		case c == ',' && depth == 0:
+			steps = append(steps, s[start:i])
+			start = i + 1
+		}
+	}
+	if depth != 0 || inClass {
+		return nil, fmt.Errorf("unbalanced brackets in pipeline(%s), use JSON form for such values", s)
+	}
+	return append(steps, s[start:]), nil
+}
+
+// TransformArgs are arguments of transform from spec.
+type TransformArgs map[string]string
+
+// Check returns error if there are arguments other than keys.
+func (s TransformArgs) Check(keys ...string) error {
+	for k := range s {
+		found := false
+		for _, key := range keys {
+			if k == key {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("unknown argument(%s), expected one of %v", k, keys)
+		}
+	}
+	return nil
+}
+
+// Float returns value of argument, or default if it is not set.
+func (s TransformArgs) Float(key string, d float64) (float64, error) {
+	v, ok := s[key]
+	if !ok {
+		return d, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, fmt.Errorf("argument(%s) is not float: %w", key, err)
+	}
+	return f, nil
+}
+
+// Int returns value of argument, or default if it is not set.
+func (s TransformArgs) Int(key string, d int) (int, error) {
+	v, ok := s[key]
+	if !ok {
+		return d, nil
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("argument(%s) is not integer: %w", key, err)
+	}
+	return i, nil
+}
+
+// List returns values of argument separated by semicolon, or nil if it is not set.
+func (s TransformArgs) List(key string) []string {
+	v, ok := s[key]
+	if !ok || v == "" {
+		return nil
+	}
+	return strings.Split(v, ";")
+}
+
+// TransformFactory makes transform from arguments.
+type TransformFactory func(args TransformArgs) (Transform, error)
+
+// Transforms is registry of transforms by name.
+type Transforms struct {
+	factories map[string]TransformFactory
+}
+
+func NewTransforms() *Transforms {
+	return &Transforms{
+		factories: map[string]TransformFactory{},
+	}
+}
+
+// NewDefaultTransforms has all transforms of this package.
+func NewDefaultTransforms() *Transforms {
+	transforms := NewTransforms()
+	transforms.Register("sum-size", func(args TransformArgs) (Transform, error) {
+		if err := args.Check("empty"); err != nil {
+			return nil, err
+		}
+		empty, err := args.Float("empty", 1)
+		if err != nil {
+			return nil, err
+		}
+		return TransformFunc(func(ctx context.Context, tree *Tree) error {
+			SumSizeImputer{EmptyLeafSize: empty}.ImputeSize(ctx, *tree)
+			return nil
+		}), nil
+	})
+	transforms.Register("weighted-heat", func(args TransformArgs) (Transform, error) {
+		if err := args.Check("empty"); err != nil {
+			return nil, err
+		}
+		empty, err := args.Float("empty", 0.5)
+		if err != nil {
+			return nil, err
+		}
+		return TransformFunc(func(ctx context.Context, tree *Tree) error {
+			WeightedHeatImputer{EmptyLeafHeat: empty}.ImputeHeat(ctx, *tree)
+			return nil
+		}), nil
+	})
+	transforms.Register("depth", func(args TransformArgs) (Transform, error) {
+		if err := args.Check("max"); err != nil {
+			return nil, err
+		}
+		maxDepth, err := args.Int("max", 0)
+		if err != nil {
+			return nil, err
+		}
+		if maxDepth < 1 {
+			return nil, fmt.Errorf("max(%d) should be positive", maxDepth)
+		}
+		return TransformFunc(func(ctx context.Context, tree *Tree) error {
+			DepthLimiter{MaxDepth: maxDepth}.LimitDepth(ctx, tree)
+			return nil
+		}), nil
+	})
+	transforms.Register("top", func(args TransformArgs) (Transform, error) {
+		if err := args.Check("n"); err != nil {
+			return nil, err
+		}
+		n, err := args.Int("n", 0)
+		if err != nil {
+			return nil, err
+		}
+		if n < 1 {
+			return nil, fmt.Errorf("n(%d) should be positive", n)
+		}
+		return TransformFunc(func(ctx context.Context, tree *Tree) error {
+			TopNPruner{N: n}.PruneTopN(ctx, tree)
+			return nil
+		}), nil
+	})
+	// parents that lost children are imputed again with same defaults as sum-size and weighted-heat
+	transforms.Register("filter", func(args TransformArgs) (Transform, error) {
+		if err := args.Check("include", "exclude"); err != nil {
+			return nil, err
+		}
+		filter, err := NewPathFilter(args.List("include"), args.List("exclude"))
+		if err != nil {
+			return nil, err
+		}
+		return TransformFunc(func(ctx context.Context, tree *Tree) error {
+			filter.FilterTree(ctx, tree)
+			SumSizeImputer{EmptyLeafSize: 1}.ImputeSize(ctx, *tree)
+			WeightedHeatImputer{EmptyLeafHeat: 0.5}.ImputeHeat(ctx, *tree)
+			return nil
+		}), nil
+	})
+	transforms.RegisterFunc("set-names", SetNamesFromPaths)
+	transforms.RegisterFunc("collapse-long-paths", CollapseLongPaths)
+	transforms.RegisterFunc("normalize-heat", func(ctx context.Context, tree *Tree) { tree.NormalizeHeat(ctx) })
+	return transforms
+}
+
+// Register adds transform. Transform with same name is replaced.
+func (s *Transforms) Register(name string, factory TransformFactory) {
+	s.factories[name] = factory
+}
+
+// RegisterFunc adds transform without arguments.
+func (s *Transforms) RegisterFunc(name string, f func(ctx context.Context, tree *Tree)) {
+	s.Register(name, func(args TransformArgs) (Transform, error) {
+		if err := args.Check(); err != nil {
+			return nil, err
+		}
+		return TransformFunc(func(ctx context.Context, tree *Tree) error {
+			f(ctx, tree)
+			return nil
+		}), nil
+	})
+}
+
+// Names of registered transforms in sorted order.
+func (s *Transforms) Names() []string {
+	names := make([]string, 0, len(s.factories))
+	for name := range s.factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewPipeline makes transforms from spec.
+func (s *Transforms) NewPipeline(spec PipelineSpec) (Pipeline, error) {
+	pipeline := make(Pipeline, 0, len(spec))
+	for i, t := range spec {
+		factory, ok := s.factories[t.Name]
+		if !ok {
+			return nil, fmt.Errorf("step(%d) unknown transform(%s), expected one of %v", i, t.Name, s.Names())
+		}
+		transform, err := factory(t.Args)
+		if err != nil {
+			return nil, fmt.Errorf("step(%d) %s: %w", i, t.Name, err)
+		}
+		pipeline = append(pipeline, PipelineStep{Name: t.Name, Transform: transform})
+	}
+	return pipeline, nil
+}
@@ -8,12 +8,15 @@ import (
 // for numerical stability
 const minHeatDifferenceForHeatmap float64 = 0.0000001
 
+// Node of tree.
+// Attributes are optional extra values that are carried from input, e.g. extra columns in CSV.
 type Node struct {
-	Path    string
-	Name    string
-	Size    float64
-	Heat    float64
-	HasHeat bool
+	Path       string
+	Name       string
+	Size       float64
+	Heat       float64
+	HasHeat    bool
+	Attributes map[string]string
 }
 
 type Tree struct {
@@ -65,33 +68,39 @@ func (t Tree) NormalizeHeat(ctx context.Context) {
 		}
 
 		n := Node{
-			Path:    node.Path,
-			Name:    node.Name,
-			Size:    node.Size,
-			Heat:    (node.Heat - minHeat) / (maxHeat - minHeat),
-			HasHeat: true,
+			Path:       node.Path,
+			Name:       node.Name,
+			Size:       node.Size,
+			Heat:       (node.Heat - minHeat) / (maxHeat - minHeat),
+			HasHeat:    true,
+			Attributes: node.Attributes,
 		}
 		t.Nodes[path] = n
 	}
 }
 
-// SetNamesFromPaths will update each node to its path leaf as name.
+// SetNamesFromPaths will update each node without name to its path leaf as name.
+// Names that are already set, like module paths, are kept.
 func SetNamesFromPaths(ctx context.Context, t *Tree) {
 	if t == nil {
 		return
 	}
 	for path, node := range t.Nodes {
+		if node.Name != "" {
+			continue
+		}
 		parts := strings.Split(node.Path, "/")
 		if len(parts) == 0 {
 			continue
 		}
 
 		t.Nodes[path] = Node{
-			Path:    node.Path,
-			Name:    parts[len(parts)-1],
-			Size:    node.Size,
-			Heat:    node.Heat,
-			HasHeat: node.HasHeat,
+			Path:       node.Path,
+			Name:       parts[len(parts)-1],
+			Size:       node.Size,
+			Heat:       node.Heat,
+			HasHeat:    node.HasHeat,
+			Attributes: node.Attributes,
 		}
 	}
 }
@@ -40,11 +40,12 @@ func (s WeightedHeatImputer) ImputeHeatNode(ctx context.Context, t Tree, node st
 		}
 
 		t.Nodes[node] = Node{
-			Path:    t.Nodes[node].Path,
-			Name:    t.Nodes[node].Name,
-			Size:    t.Nodes[node].Size,
-			Heat:    v,
-			HasHeat: true,
+			Path:       t.Nodes[node].Path,
+			Name:       t.Nodes[node].Name,
+			Size:       t.Nodes[node].Size,
+			Heat:       v,
+			HasHeat:    true,
+			Attributes: t.Nodes[node].Attributes,
 		}
 	}
 }